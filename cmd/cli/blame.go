@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/comparison"
+	"github.com/spf13/cobra"
+)
+
+var blameCmd = &cobra.Command{
+	Use:   "blame [secret-path]",
+	Short: "Show the version that introduced the current value of each key of a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configs, err := readConfigs()
+		if err != nil {
+			return err
+		}
+
+		// Validate that redact_secrets warning is shown if disabled
+		warnIfRedactionDisabled(configs)
+
+		result, err := comparison.BlameSecret(env, args[0], env, kvEngine, configs)
+		if err != nil {
+			return fmt.Errorf("failed to blame secret: %w", err)
+		}
+
+		printBlame(result)
+		return nil
+	},
+}
+
+// printBlame prints one line per key with the version, date and author of its current value
+func printBlame(result *comparison.BlameResult) {
+	fmt.Printf("Blame for %s in %s (%s) at version %s\n", result.Path, result.Instance, result.StoreType, result.Version)
+	fmt.Println("----------------------------------------")
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KEY\tVERSION\tDATE\tAUTHOR\tVALUE")
+
+	for _, line := range result.Lines {
+		date := "-"
+		if !line.CreatedTime.IsZero() {
+			date = line.CreatedTime.UTC().Format(time.RFC3339)
+		}
+
+		author := line.Author
+		if author == "" {
+			author = "-"
+		}
+
+		value := line.Value
+		if line.IsRedacted {
+			value = "(redacted)"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", line.Key, line.Version, date, author, value)
+	}
+
+	writer.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(blameCmd)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configOutput string
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check and inspect the configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check every environment and report unknown fields",
+	Long:  "Merge the system, user and project config files and VAULT_PROMOTER_* overrides, then check every environment, unknown fields and unset ${VAR} references.",
+	Args:  cobra.NoArgs,
+	// A failed validation is the answer, not a usage mistake
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configs, err := config.LoadConfigs(configPath)
+		if err != nil {
+			return fmt.Errorf("configuration is invalid: %w", err)
+		}
+
+		fmt.Println("Config files, lowest precedence first:")
+		for _, source := range configs.Sources {
+			fmt.Printf("  %s\n", source)
+		}
+
+		if len(configs.Warnings) > 0 {
+			fmt.Printf("Configuration has %d problem(s):\n", len(configs.Warnings))
+			for _, warning := range configs.Warnings {
+				fmt.Printf("  - %s\n", warning)
+			}
+			return fmt.Errorf("configuration is invalid")
+		}
+
+		fmt.Printf("Configuration is valid (%d environments)\n", len(configs.Environments))
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration with defaults applied",
+	Long:  "Print the configuration as the commands see it, merged from every config file and override with every default filled in. Tokens and other credentials are never printed, only where they are read from.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configs, err := readConfigs()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(configs.Effective(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode configuration: %w", err)
+		}
+
+		switch configOutput {
+		case config.FormatJSON:
+			fmt.Println(string(data))
+		case config.FormatYAML:
+			// Go through a node so the fields keep their order
+			var node yaml.Node
+			if err := yaml.Unmarshal(data, &node); err != nil {
+				return fmt.Errorf("failed to encode configuration: %w", err)
+			}
+			blockStyle(&node)
+
+			out, err := yaml.Marshal(&node)
+			if err != nil {
+				return fmt.Errorf("failed to encode configuration: %w", err)
+			}
+			for _, source := range configs.Sources {
+				fmt.Printf("# from %s\n", source)
+			}
+			fmt.Print(string(out))
+		default:
+			return fmt.Errorf("unsupported output format %s, use yaml or json", configOutput)
+		}
+
+		return nil
+	},
+}
+
+// blockStyle resets the flow style that JSON input leaves on YAML nodes
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func init() {
+	configShowCmd.Flags().StringVarP(&configOutput, "output", "o", config.FormatYAML, "Output format (yaml or json)")
+
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
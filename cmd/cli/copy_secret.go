@@ -10,10 +10,8 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/secretz/vault-promoter/pkg/awssecretsmanager"
 	"github.com/secretz/vault-promoter/pkg/comparison"
 	"github.com/secretz/vault-promoter/pkg/config"
-	"github.com/secretz/vault-promoter/pkg/vault"
 )
 
 // CopyLogEntry tracks copy operations for auditing purposes
@@ -31,7 +29,7 @@ type CopyLogEntry struct {
 }
 
 // logCopyOperation maintains audit trail for compliance and troubleshooting
-func logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath string, result *comparison.CopyResult, logFile string) {
+func logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath string, result *comparison.CopyResult, configs *config.Configs, logFile string) {
 	entry := CopyLogEntry{
 		Timestamp:   time.Now().Format(time.RFC3339),
 		SourceEnv:   sourceEnv,
@@ -45,10 +43,15 @@ func logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath string, resul
 		Keys:        make(map[string]interface{}),
 	}
 
-	// Redact sensitive values for security
+	// The log outlives the run, so sensitive values are never written to it,
+	// whatever the display settings say. Values the target would hide are
+	// left out too.
 	if result.Keys != nil {
+		redaction := configs.RedactionFor(targetEnv, targetPath)
+		sensitiveRules, rulesErr := config.ParseKeyRules(configs.GetSensitiveKeys())
 		for k, v := range result.Keys {
-			if isSensitiveKey(k) {
+			sensitive := result.Sensitive[k] || rulesErr != nil || sensitiveRules.Contains(k)
+			if sensitive || redaction.Decide(k, false).Redacted {
 				entry.Keys[k] = "(redacted)"
 			} else {
 				entry.Keys[k] = v
@@ -62,8 +65,8 @@ func logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath string, resul
 		return
 	}
 
-	// Use append mode to maintain history and create if needed
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Use append mode to maintain history and create if needed, readable by the owner only
+	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
 	if err != nil {
 		fmt.Printf("Error opening log file: %v\n", err)
 		return
@@ -83,23 +86,6 @@ func logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath string, resul
 	fmt.Printf("Copy operation logged to %s\n", logFile)
 }
 
-// isSensitiveKey identifies keys that should have their values redacted in logs
-func isSensitiveKey(key string) bool {
-	sensitivePatterns := []string{
-		"password", "secret", "token", "key", "credential", "auth", "pwd", "pass",
-		"apikey", "api_key", "access_key", "secret_key", "private_key", "cert", "certificate",
-	}
-
-	lowerKey := strings.ToLower(key)
-	for _, pattern := range sensitivePatterns {
-		if strings.Contains(lowerKey, pattern) {
-			return true
-		}
-	}
-
-	return false
-}
-
 func promptForConfirmation(message string) bool {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Printf("%s [y/N]: ", message)
@@ -137,8 +123,8 @@ func init() {
 		Short: "Copy secrets between environments or stores",
 		Long: `Copy secrets between environments or stores.
 
-This command allows you to copy secrets between different environments and store types
-(Vault and AWS Secrets Manager). You can specify which keys to copy and whether to
+This command allows you to copy secrets between different environments and any of the
+configured store types. You can specify which keys to copy and whether to
 overwrite existing keys in the target.
 
 By default, the command will prompt for confirmation before making any changes.
@@ -185,23 +171,24 @@ All copy operations are logged to the specified log file (--log-to) in JSON form
 			}
 
 			// Load configuration
-			configs, err := config.ReadConfigs(configPath)
+			configs, err := readConfigs()
 			if err != nil {
 				fmt.Printf("Error loading config: %v\n", err)
 				os.Exit(1)
 			}
 
-			// Get source and target configs
-			sourceConfig, err := configs.GetEnvironmentConfig(sourceEnv)
+			targetConfig, err := configs.GetEnvironmentConfig(targetEnv)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 
-			targetConfig, err := configs.GetEnvironmentConfig(targetEnv)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				os.Exit(1)
+			// Default target KV to source KV, or to "secret" when the source has none
+			if targetKV == "" {
+				targetKV = sourceKV
+			}
+			if targetKV == "" && targetConfig.Store == config.StoreVault {
+				targetKV = "secret"
 			}
 
 			// Create copy options
@@ -210,152 +197,28 @@ All copy operations are logged to the specified log file (--log-to) in JSON form
 				CopyConfig:   copyConfig,
 				CopySecrets:  copySecrets,
 				OnlyCopyKeys: onlyCopyKeys,
+				Prune:        prune,
 			}
 
-			// Determine the copy operation based on store types
-			if sourceConfig.Store == targetConfig.Store {
-				// Same store type
-				switch sourceConfig.Store {
-				case "vault":
-					// Source KV must be specified for Vault
-					if sourceKV == "" {
-						fmt.Println("Error: Source KV engine must be specified when using Vault")
-						os.Exit(1)
-					}
-
-					// Default target KV to source KV if not specified
-					if targetKV == "" {
-						targetKV = sourceKV
-					}
-
-					// Create target Vault client directly
-					vaultClient, err := vault.NewClient(targetConfig, configs, vault.Environment(targetEnv), targetKV)
-					if err != nil {
-						fmt.Printf("Error creating target Vault client: %v\n", err)
-						os.Exit(1)
-					}
-
-					// Ensure the KV engine exists in target
-					err = vaultClient.EnsureKVEngineExists(targetKV)
-					if err != nil {
-						fmt.Printf("Error ensuring KV engine exists: %v\n", err)
-						os.Exit(1)
-					}
-
-					// Convert options
-					vaultOptions := vault.CopyOptions{
-						Overwrite:    options.Overwrite,
-						CopyConfig:   options.CopyConfig,
-						CopySecrets:  options.CopySecrets,
-						OnlyCopyKeys: options.OnlyCopyKeys,
-					}
-
-					// Copy the secret
-					err = vaultClient.CopySecret(sourcePath, targetPath, vaultOptions)
-					if err != nil {
-						fmt.Printf("Error copying secret: %v\n", err)
-						os.Exit(1)
-					}
-
-					// Create a result for logging
-					result := &comparison.CopyResult{
-						SourcePath:      sourcePath,
-						TargetPath:      targetPath,
-						SourceStoreType: "vault",
-						TargetStoreType: "vault",
-						Success:         true,
-						Message:         "Successfully copied secret",
-					}
-
-					// Log the copy operation
-					logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath, result, logToFile)
-
-					fmt.Printf("Successfully copied secret from %s/%s to %s/%s\n", sourceEnv, sourcePath, targetEnv, targetPath)
-
-				case "awssecretsmanager":
-					// Create AWS clients
-					_, err := awssecretsmanager.NewClient(sourceConfig, configs)
-					if err != nil {
-						fmt.Printf("Error creating source AWS client: %v\n", err)
-						os.Exit(1)
-					}
-
-					awsClient, err := awssecretsmanager.NewClient(targetConfig, configs)
-					if err != nil {
-						fmt.Printf("Error creating target AWS client: %v\n", err)
-						os.Exit(1)
-					}
-
-					// Convert options
-					awsOptions := awssecretsmanager.CopyOptions{
-						Overwrite:    options.Overwrite,
-						CopyConfig:   options.CopyConfig,
-						CopySecrets:  options.CopySecrets,
-						OnlyCopyKeys: options.OnlyCopyKeys,
-						Prune:        prune,
-					}
-
-					// Copy the secret
-					err = awsClient.CopySecret(sourcePath, targetPath, awsOptions, configs)
-					if err != nil {
-						fmt.Printf("Error copying secret: %v\n", err)
-						os.Exit(1)
-					}
-
-					// Create a result for logging
-					result := &comparison.CopyResult{
-						SourcePath:      sourcePath,
-						TargetPath:      targetPath,
-						SourceStoreType: "awssecretsmanager",
-						TargetStoreType: "awssecretsmanager",
-						Success:         true,
-						Message:         "Successfully copied secret",
-					}
-
-					// Log the copy operation
-					logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath, result, logToFile)
-
-					fmt.Printf("Successfully copied secret from %s/%s to %s/%s\n", sourceEnv, sourcePath, targetEnv, targetPath)
-
-				default:
-					fmt.Printf("Unsupported store type: %s\n", sourceConfig.Store)
-					os.Exit(1)
-				}
-			} else {
-				// Cross-store copy (Vault <-> AWS)
-				// Source KV must be specified when source is Vault
-				if sourceKV == "" && sourceConfig.Store == "vault" {
-					fmt.Println("Error: Source KV engine must be specified when using Vault")
-					os.Exit(1)
-				}
-
-				// Default target KV if not specified and target is Vault
-				if targetKV == "" && targetConfig.Store == "vault" {
-					targetKV = "secret"
-				}
-
-				// Perform cross-store copy
-				result, err := comparison.CopyVaultWithAWS(
-					sourceEnv, targetEnv, sourcePath, targetPath,
-					sourceEnv, targetEnv, sourceKV, targetKV,
-					configs,
-					options,
-				)
-
-				if err != nil {
-					fmt.Printf("Error copying secret: %v\n", err)
-					os.Exit(1)
-				}
+			result, err := comparison.CopyBetweenStores(
+				sourceEnv, targetEnv, sourcePath, targetPath,
+				sourceEnv, targetEnv, sourceKV, targetKV,
+				configs,
+				options,
+			)
+			if err != nil {
+				fmt.Printf("Error copying secret: %v\n", err)
+				os.Exit(1)
+			}
 
-				// Log the copy operation
-				logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath, result, logToFile)
+			// Log the copy operation
+			logCopyOperation(sourceEnv, targetEnv, sourcePath, targetPath, result, configs, logToFile)
 
-				if result.Success {
-					fmt.Println(result.Message)
-				} else {
-					fmt.Printf("Failed to copy secret: %s\n", result.Message)
-					os.Exit(1)
-				}
+			if result.Success {
+				fmt.Println(result.Message)
+			} else {
+				fmt.Printf("Failed to copy secret: %s\n", result.Message)
+				os.Exit(1)
 			}
 		},
 	}
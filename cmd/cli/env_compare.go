@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/secretz/vault-promoter/pkg/comparison"
+	"github.com/spf13/cobra"
+)
+
+var (
+	envCompareTarget   string
+	envCompareInstance string
+)
+
+var envCompareCmd = &cobra.Command{
+	Use:   "env-compare [app]",
+	Short: "Compare an app's secret with the next environment of the promotion chain",
+	Long:  "Compare the secret of an app in the environment given by --env with the one in --target-env, or the next environment of the promotion chain. Paths are built from the path template of each store, e.g. app/dev/config and app/uat/config with the default {app}/{env}/{suffix}.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configs, err := readConfigs()
+		if err != nil {
+			return err
+		}
+
+		// Validate that redact_secrets warning is shown if disabled
+		warnIfRedactionDisabled(configs)
+
+		target := envCompareTarget
+		if target == "" {
+			target, err = configs.NextEnvironment(env)
+			if err != nil {
+				return fmt.Errorf("%w, pass --target-env", err)
+			}
+		}
+
+		result, err := comparison.CompareEnvironments(envCompareInstance, args[0], env, target, pathSuffix, kvEngine, configs)
+		if err != nil {
+			return fmt.Errorf("failed to compare environments: %w", err)
+		}
+
+		printComparisonResult(result)
+		return nil
+	},
+}
+
+func init() {
+	envCompareCmd.Flags().StringVar(&envCompareTarget, "target-env", "", "Environment to compare with (default: the next one in the promotion chain)")
+	envCompareCmd.Flags().StringVar(&envCompareInstance, "instance", "", "Store holding both environments (default: the store configured under each environment's name)")
+
+	rootCmd.AddCommand(envCompareCmd)
+}
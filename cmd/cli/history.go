@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/comparison"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [secret-path]",
+	Short: "Show the key-level changelog of a secret across its versions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configs, err := readConfigs()
+		if err != nil {
+			return err
+		}
+
+		// Validate that redact_secrets warning is shown if disabled
+		warnIfRedactionDisabled(configs)
+
+		result, err := comparison.SecretHistory(env, args[0], env, kvEngine, configs)
+		if err != nil {
+			return fmt.Errorf("failed to get secret history: %w", err)
+		}
+
+		printHistory(result)
+		return nil
+	},
+}
+
+// printHistory prints the versions of a secret with the keys each one added, changed or removed
+func printHistory(result *comparison.HistoryResult) {
+	fmt.Printf("History of %s in %s (%s)\n", result.Path, result.Instance, result.StoreType)
+	fmt.Println("----------------------------------------")
+
+	if len(result.Entries) == 0 {
+		fmt.Println("\nNo versions found")
+		return
+	}
+
+	for _, entry := range result.Entries {
+		header := fmt.Sprintf("\nVersion %s", entry.Version)
+		if !entry.CreatedTime.IsZero() {
+			header += fmt.Sprintf(" | %s", entry.CreatedTime.UTC().Format(time.RFC3339))
+		}
+		if entry.Author != "" {
+			header += fmt.Sprintf(" | by %s", entry.Author)
+		}
+		fmt.Println(header)
+
+		if entry.Deleted {
+			fmt.Println("  (deleted, contents not available)")
+			continue
+		}
+
+		if len(entry.Changes) == 0 {
+			fmt.Println("  (no key changes)")
+			continue
+		}
+
+		for _, change := range entry.Changes {
+			switch {
+			case change.IsRedacted:
+				fmt.Printf("%s %s (redacted)\n", changeLabel(change.Status), change.Key)
+			case change.Status == "+":
+				fmt.Printf("%s %s = %s\n", changeLabel(change.Status), change.Key, change.Current)
+			case change.Status == "-":
+				fmt.Printf("%s %s (was %s)\n", changeLabel(change.Status), change.Key, change.Target)
+			default:
+				fmt.Printf("%s %s: %s -> %s\n", changeLabel(change.Status), change.Key, change.Target, change.Current)
+			}
+		}
+	}
+}
+
+// changeLabel turns a diff status into the word shown in the changelog
+func changeLabel(status string) string {
+	switch status {
+	case "+":
+		return "  + added  "
+	case "-":
+		return "  - removed"
+	default:
+		return "  * changed"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
@@ -3,8 +3,8 @@ package main
 import (
 	"fmt"
 
+	"github.com/secretz/vault-promoter/pkg/comparison"
 	"github.com/secretz/vault-promoter/pkg/config"
-	"github.com/secretz/vault-promoter/pkg/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -43,104 +43,39 @@ var instanceCompareCmd = &cobra.Command{
 		}
 
 		// Validate that redact_secrets warning is shown if disabled
-		if configs.RedactSecrets != nil && !*configs.RedactSecrets {
-			fmt.Println("WARNING: Secret redaction is disabled. Sensitive values may be displayed in plaintext.")
+		warnIfRedactionDisabled(configs)
+
+		// Fall back to the source settings for anything not given for the target
+		targetPath := targetPathInstance
+		if targetPath == "" {
+			targetPath = configPathInstance
+		}
+		targetEnvName := targetEnvInstance
+		if targetEnvName == "" {
+			targetEnvName = envInstance
+		}
+		targetKVName := targetKVInstance
+		if targetKVName == "" {
+			targetKVName = kvEngineInstance
 		}
 
 		// Perform the comparison
-		result, err := vault.CompareVaultInstances(
+		result, err := comparison.CompareStores(
 			sourceInstance,
 			targetInstance,
 			configPathInstance,
+			targetPath,
 			envInstance,
+			targetEnvName,
 			kvEngineInstance,
-			targetPathInstance,
-			targetEnvInstance,
-			targetKVInstance,
+			targetKVName,
 			configs,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to compare vault instances: %w", err)
 		}
 
-		// Print the results
-		fmt.Printf("Source Path: %s | Target Path: %s\n", result.SourcePath, result.TargetPath)
-		fmt.Printf("Source Instance: %s | Target Instance: %s\n", sourceInstance, targetInstance)
-		fmt.Printf("Source Env: %s | Target Env: %s\n", result.SourceEnv, result.TargetEnv)
-		fmt.Printf("Source KV Engine: %s | Target KV Engine: %s\n", result.SourceKVEngine, result.TargetKVEngine)
-		fmt.Println("----------------------------------------")
-
-		if len(result.MissingInSource) > 0 {
-			fmt.Printf("\nSecrets missing in source instance (%s):\n", sourceInstance)
-			for _, path := range result.MissingInSource {
-				fmt.Printf("  - %s\n", path)
-			}
-		}
-
-		if len(result.MissingInTarget) > 0 {
-			fmt.Printf("\nSecrets missing in target instance (%s):\n", targetInstance)
-			for _, path := range result.MissingInTarget {
-				fmt.Printf("  - %s\n", path)
-			}
-		}
-
-		if len(result.Comparisons) == 0 {
-			fmt.Println("\nNo differences found!")
-			return nil
-		}
-
-		// Print the comparisons
-		for _, comparison := range result.Comparisons {
-			fmt.Printf("\nComparison for: %s\n", comparison.Path)
-			fmt.Println("----------------------------------------")
-
-			for _, diff := range comparison.Diffs {
-				statusPrefix := "  "
-				statusSymbol := ""
-				if diff.Status == "+" {
-					statusPrefix = "+ "
-					statusSymbol = "+ "
-				} else if diff.Status == "-" {
-					statusPrefix = "- "
-					statusSymbol = "- "
-				} else if diff.Status == "*" {
-					statusPrefix = "* "
-					statusSymbol = "* "
-				}
-
-				// Special handling for INFO key
-				if diff.Key == "INFO" {
-					if diff.Current != "" {
-						fmt.Printf("%s%s\n", statusPrefix, diff.Current)
-					}
-					if diff.Target != "" {
-						fmt.Printf("%s%s\n", statusPrefix, diff.Target)
-					}
-					continue
-				}
-
-				fmt.Printf("%sKey: %s\n", statusPrefix, diff.Key)
-
-				if diff.Current != "" {
-					if diff.IsRedacted {
-						fmt.Printf("%sSource (%s): (redacted)\n", statusSymbol, sourceInstance)
-					} else {
-						fmt.Printf("%sSource (%s): %s\n", statusSymbol, sourceInstance, diff.Current)
-					}
-				}
-
-				if diff.Target != "" {
-					if diff.IsRedacted {
-						fmt.Printf("%sTarget (%s): (redacted)\n", statusSymbol, targetInstance)
-					} else {
-						fmt.Printf("%sTarget (%s): %s\n", statusSymbol, targetInstance, diff.Target)
-					}
-				}
-
-				fmt.Println("---")
-			}
-		}
-
+		printComparisonResult(result)
 		return nil
 	},
 }
@@ -150,7 +85,7 @@ func init() {
 	instanceCompareCmd.Flags().StringVar(&sourceInstance, "source", "dev", "Source vault instance (from config file)")
 	instanceCompareCmd.Flags().StringVar(&targetInstance, "target", "uat", "Target vault instance (from config file)")
 	instanceCompareCmd.Flags().StringVar(&kvEngineInstance, "kv-engine", "", "Source KV engine name (required)")
-	instanceCompareCmd.Flags().StringVar(&configPathInstance, "config-path", "", "Full path to the source secret (required)")
+	instanceCompareCmd.Flags().StringVar(&configPathInstance, "config-path", "", "Full path to the source secret, optionally pinned to a version with path@version (required)")
 	instanceCompareCmd.Flags().StringVar(&envInstance, "env", "", "Source environment name in the config (required)")
 
 	// Optional target-specific flags
@@ -169,9 +104,11 @@ func init() {
 
 // Utility function to read configs
 func readConfigs() (*config.Configs, error) {
-	configs, err := config.ReadConfigs(configPath)
+	configs, err := config.LoadConfigs(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
 	}
+
+	warnConfigProblems(configs)
 	return configs, nil
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/secretz/vault-promoter/pkg/comparison"
+	"github.com/secretz/vault-promoter/pkg/config"
+)
+
+// warnIfRedactionDisabled reminds the user that values will be printed in plaintext
+func warnIfRedactionDisabled(configs *config.Configs) {
+	if configs.RedactionDisabled() {
+		fmt.Println("WARNING: Secret redaction is disabled for some or all secrets. Sensitive values may be displayed in plaintext.")
+	}
+}
+
+// warnConfigProblems prints the problems found while reading the config file
+func warnConfigProblems(configs *config.Configs) {
+	for _, warning := range configs.Warnings {
+		fmt.Printf("WARNING: %s\n", warning)
+	}
+}
+
+// printComparisonResult prints a comparison in the format shared by all compare commands
+func printComparisonResult(result *comparison.ComparisonResult) {
+	sourceLabel := result.SourceInstance
+	targetLabel := result.TargetInstance
+
+	// Print the results
+	fmt.Printf("Source Path: %s | Target Path: %s\n", result.SourcePath, result.TargetPath)
+	fmt.Printf("Source Instance: %s | Target Instance: %s\n", result.SourceInstance, result.TargetInstance)
+	fmt.Printf("Source Env: %s | Target Env: %s\n", result.SourceEnv, result.TargetEnv)
+	fmt.Printf("Source Store Type: %s | Target Store Type: %s\n", result.SourceStoreType, result.TargetStoreType)
+	if result.SourceKVEngine != "" || result.TargetKVEngine != "" {
+		fmt.Printf("Source KV Engine: %s | Target KV Engine: %s\n", result.SourceKVEngine, result.TargetKVEngine)
+	}
+	fmt.Println("----------------------------------------")
+
+	if len(result.MissingInSource) > 0 {
+		fmt.Printf("\nSecrets missing in source instance (%s):\n", sourceLabel)
+		for _, path := range result.MissingInSource {
+			fmt.Printf("  - %s\n", path)
+		}
+	}
+
+	if len(result.MissingInTarget) > 0 {
+		fmt.Printf("\nSecrets missing in target instance (%s):\n", targetLabel)
+		for _, path := range result.MissingInTarget {
+			fmt.Printf("  - %s\n", path)
+		}
+	}
+
+	if len(result.Comparisons) == 0 {
+		fmt.Println("\nNo differences found!")
+		return
+	}
+
+	// Print the comparisons
+	for _, item := range result.Comparisons {
+		fmt.Printf("\nComparison for: %s\n", item.Path)
+		fmt.Println("----------------------------------------")
+		printDiffs(item.Diffs, sourceLabel, targetLabel)
+	}
+}
+
+// printDiffs prints the key-level differences of a comparison
+func printDiffs(diffs []comparison.DiffItem, sourceLabel, targetLabel string) {
+	for _, diff := range diffs {
+		statusPrefix := "  "
+		if diff.Status == "+" {
+			statusPrefix = "+ "
+		} else if diff.Status == "-" {
+			statusPrefix = "- "
+		} else if diff.Status == "*" {
+			statusPrefix = "* "
+		}
+
+		// Special handling for INFO and ERROR keys
+		if diff.Key == "INFO" || diff.Key == "ERROR" {
+			if diff.Current != "" {
+				fmt.Printf("%s%s\n", statusPrefix, diff.Current)
+			}
+			if diff.Target != "" {
+				fmt.Printf("%s%s\n", statusPrefix, diff.Target)
+			}
+			continue
+		}
+
+		fmt.Printf("%sKey: %s\n", statusPrefix, diff.Key)
+
+		if diff.Current != "" {
+			if diff.IsRedacted {
+				fmt.Printf("%sSource (%s): (redacted)\n", statusPrefix, sourceLabel)
+			} else {
+				fmt.Printf("%sSource (%s): %s\n", statusPrefix, sourceLabel, diff.Current)
+			}
+		}
+
+		if diff.Target != "" {
+			if diff.IsRedacted {
+				fmt.Printf("%sTarget (%s): (redacted)\n", statusPrefix, targetLabel)
+			} else {
+				fmt.Printf("%sTarget (%s): %s\n", statusPrefix, targetLabel, diff.Target)
+			}
+		}
+
+		fmt.Println("---")
+	}
+}
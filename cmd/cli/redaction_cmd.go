@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var redactionPath string
+
+var redactionCmd = &cobra.Command{
+	Use:   "redaction",
+	Short: "Inspect how secret values are redacted",
+}
+
+var redactionExplainCmd = &cobra.Command{
+	Use:   "explain [key]",
+	Short: "Show whether a key is redacted and which rule decides it",
+	Long:  "Resolve the redaction settings for the environment given by --env and the secret given by --path, then show whether the value of key is redacted and which rule decided it. Keys a store marks as sensitive, such as SSM SecureString parameters or plain string AWS secrets, are also redacted unless an allow-list rule matches them.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configs, err := readConfigs()
+		if err != nil {
+			return err
+		}
+
+		key := args[0]
+		redaction := configs.RedactionFor(env, redactionPath)
+		decision := redaction.Decide(key, false)
+
+		fmt.Printf("Key:         %s\n", key)
+		fmt.Printf("Environment: %s\n", env)
+		if redactionPath != "" {
+			fmt.Printf("Path:        %s\n", redactionPath)
+		}
+
+		policies := "none"
+		if len(redaction.Policies) > 0 {
+			policies = strings.Join(redaction.Policies, ", ")
+		}
+		fmt.Printf("Policies:    %s\n", policies)
+
+		result := "shown"
+		if decision.Redacted {
+			result = "redacted"
+		}
+		fmt.Printf("Result:      %s (%s)\n", result, decision.Reason)
+
+		return nil
+	},
+}
+
+func init() {
+	redactionExplainCmd.Flags().StringVar(&redactionPath, "path", "", "Path of the secret holding the key, for path-specific policies")
+
+	redactionCmd.AddCommand(redactionExplainCmd)
+	rootCmd.AddCommand(redactionCmd)
+}
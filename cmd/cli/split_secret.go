@@ -9,9 +9,9 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/secretz/vault-promoter/pkg/awssecretsmanager"
+	"github.com/secretz/vault-promoter/pkg/comparison"
 	"github.com/secretz/vault-promoter/pkg/config"
-	"github.com/secretz/vault-promoter/pkg/vault"
+	"github.com/secretz/vault-promoter/pkg/store"
 )
 
 // SplitLogEntry tracks split operations for auditing purposes
@@ -128,187 +128,105 @@ All split operations are logged to the specified log file (--log-to) in JSON for
 				}
 			}
 
-			configs, err := config.ReadConfigs(configPath)
+			configs, err := readConfigs()
 			if err != nil {
 				fmt.Printf("Error loading config: %v\n", err)
 				os.Exit(1)
 			}
 
-			sourceConfig, err := configs.GetEnvironmentConfig(sourceEnv)
+			// Default target KV to source KV if not specified
+			if targetKV == "" {
+				targetKV = sourceKV
+			}
+
+			sourceStore, err := comparison.NewStore(sourceEnv, configs, comparison.StoreOptions{Env: sourceEnv, KVEngine: sourceKV})
 			if err != nil {
-				fmt.Printf("Error getting source environment config: %v\n", err)
+				fmt.Printf("Error creating source store client: %v\n", err)
 				os.Exit(1)
 			}
+			defer comparison.CloseStore(sourceStore)
 
-			storeType := sourceConfig.Store
+			storeType := sourceStore.Type()
 			fmt.Printf("Source store type: %s\n", storeType)
 
-			// Get target environment config
-			targetConfig, err := configs.GetEnvironmentConfig(targetEnv)
+			targetStore, err := comparison.NewStore(targetEnv, configs, comparison.StoreOptions{Env: targetEnv, KVEngine: targetKV})
 			if err != nil {
-				fmt.Printf("Error getting target environment config: %v\n", err)
+				fmt.Printf("Error creating target store client: %v\n", err)
+				comparison.CloseStore(sourceStore)
 				os.Exit(1)
 			}
+			defer comparison.CloseStore(targetStore)
 
-			// Check if source and target store types match
-			targetStoreType := targetConfig.Store
-			fmt.Printf("Target store type: %s\n", targetStoreType)
-
-			// Handle Vault-specific configuration if source is Vault
-			if storeType == "vault" {
-				// Source KV must be specified for Vault
-				if sourceKV == "" {
-					fmt.Println("Error: Source KV engine must be specified when using Vault")
-					os.Exit(1)
-				}
-
-				// Default target KV to source KV if not specified
-				if targetKV == "" {
-					targetKV = sourceKV
-				}
+			// os.Exit skips deferred calls, so close the stores before exiting
+			exit := func(code int) {
+				comparison.CloseStore(sourceStore)
+				comparison.CloseStore(targetStore)
+				os.Exit(code)
+			}
 
-				// Create Vault client
-				vaultClient, err := vault.NewClient(sourceConfig, configs, vault.Environment(sourceEnv), sourceKV)
-				if err != nil {
-					fmt.Printf("Error creating Vault client: %v\n", err)
-					os.Exit(1)
-				}
+			fmt.Printf("Target store type: %s\n", targetStore.Type())
 
-				// Ensure the target KV engine exists
-				err = vaultClient.EnsureKVEngineExists(targetKV)
-				if err != nil {
-					fmt.Printf("Error ensuring KV engine exists: %v\n", err)
-					os.Exit(1)
+			// Make sure the target location exists, e.g. the KV engine in Vault
+			if provisioner, ok := targetStore.(store.Provisioner); ok {
+				if err := provisioner.Provision(); err != nil {
+					fmt.Printf("Error preparing target store: %v\n", err)
+					exit(1)
 				}
 			}
 
-			// Process based on store type
-			var sourceSecret map[string]interface{}
-			var isJSON bool
-			var sourceClient interface{}
-			var targetClient interface{}
-
 			// First, get the source secret and check if target exists
-			if storeType == "vault" {
-				// Get source Vault client
-				vaultSourceClient, err := vault.NewClient(sourceConfig, configs, vault.Environment(sourceEnv), sourceKV)
-				if err != nil {
-					fmt.Printf("Error creating source Vault client: %v\n", err)
-					os.Exit(1)
-				}
-				sourceClient = vaultSourceClient
-
-				// Get source secret from Vault
-				secret, err := vaultSourceClient.GetSecret(sourcePath)
-				if err != nil {
-					fmt.Printf("Error getting source secret: %v\n", err)
-					os.Exit(1)
-				}
-
-				// Convert the KVSecret to a map
-				sourceSecret = secret.Data
-				isJSON = true // Vault secrets are always structured as JSON
-
-				// Get target Vault client (may be the same as source)
-				vaultTargetClient, err := vault.NewClient(targetConfig, configs, vault.Environment(targetEnv), targetKV)
-				if err != nil {
-					fmt.Printf("Error creating target Vault client: %v\n", err)
-					os.Exit(1)
-				}
-				targetClient = vaultTargetClient
-
-				// Check if target already exists
-				_, err = vaultTargetClient.GetSecret(targetPath)
-				if err == nil {
-					fmt.Printf("Error: Target path %s already exists. Split operation requires a new target path.\n", targetPath)
-					os.Exit(1)
-				} else if !strings.Contains(err.Error(), "not found") {
-					// If the error is not a 'not found' error, it's a different error
-					fmt.Printf("Error checking target path: %v\n", err)
-					os.Exit(1)
-				}
-			} else if storeType == "awssecretsmanager" {
-				// Get source AWS Secrets Manager client
-				awsSourceClient, err := awssecretsmanager.NewClient(sourceConfig, configs)
-				if err != nil {
-					fmt.Printf("Error creating source AWS Secrets Manager client: %v\n", err)
-					os.Exit(1)
-				}
-				sourceClient = awsSourceClient
-
-				// Get source secret from AWS Secrets Manager
-				sourceSecret, isJSON, err = awsSourceClient.GetSecret(sourcePath)
-				if err != nil {
-					fmt.Printf("Error getting source secret: %v\n", err)
-					os.Exit(1)
-				}
+			secret, err := sourceStore.GetSecret(sourcePath)
+			if err != nil {
+				fmt.Printf("Error getting source secret: %v\n", err)
+				exit(1)
+			}
 
-				// Check if this is a non-JSON secret
-				if !isJSON {
-					fmt.Println("Error: Source secret is not in JSON format. Split operation only works with JSON-formatted secrets.")
-					os.Exit(1)
-				}
+			// Check if this is a non-JSON secret
+			if !secret.IsJSON {
+				fmt.Println("Error: Source secret is not in JSON format. Split operation only works with JSON-formatted secrets.")
+				exit(1)
+			}
+			sourceSecret := secret.Data
 
-				// Get target AWS Secrets Manager client (may be the same as source)
-				awsTargetClient, err := awssecretsmanager.NewClient(targetConfig, configs)
-				if err != nil {
-					fmt.Printf("Error creating target AWS Secrets Manager client: %v\n", err)
-					os.Exit(1)
-				}
-				targetClient = awsTargetClient
-
-				// Check if target already exists
-				_, _, err = awsTargetClient.GetSecret(targetPath)
-				if err == nil {
-					fmt.Printf("Error: Target path %s already exists. Split operation requires a new target path.\n", targetPath)
-					os.Exit(1)
-				} else if !strings.Contains(err.Error(), "not found") {
-					// If the error is not a 'not found' error, it's a different error
-					fmt.Printf("Error checking target path: %v\n", err)
-					os.Exit(1)
-				}
-			} else {
-				fmt.Printf("Error: Unsupported store type: %s. Only 'vault' and 'awssecretsmanager' are supported.\n", storeType)
-				os.Exit(1)
+			exists, err := targetStore.SecretExists(targetPath)
+			if err != nil {
+				fmt.Printf("Error checking target path: %v\n", err)
+				exit(1)
+			}
+			if exists {
+				fmt.Printf("Error: Target path %s already exists. Split operation requires a new target path.\n", targetPath)
+				exit(1)
 			}
 
 			// Validate source secret
 			if len(sourceSecret) == 0 {
 				fmt.Println("Error: Source secret is empty")
-				os.Exit(1)
+				exit(1)
 			}
 
 			sensitiveKeys := configs.GetSensitiveKeys()
 			if len(sensitiveKeys) == 0 {
 				fmt.Println("Error: No sensitive keys defined in configuration. Nothing to split.")
-				os.Exit(1)
+				exit(1)
 			}
 
 			fmt.Printf("Found %d sensitive key patterns defined in config: %s\n",
 				len(sensitiveKeys), strings.Join(sensitiveKeys, ", "))
 
+			sensitiveRules, err := config.ParseKeyRules(sensitiveKeys)
+			if err != nil {
+				fmt.Printf("Error: Invalid sensitive_keys: %v\n", err)
+				exit(1)
+			}
+
 			sensitiveData := make(map[string]interface{})
 			newSourceData := make(map[string]interface{})
 			splitKeysList := []string{}
 
-			if len(sensitiveKeys) == 0 {
-				fmt.Println("Error: No sensitive keys defined in configuration. Nothing to split.")
-				os.Exit(1)
-			}
-
 			foundSensitiveKeys := false
 			for k, v := range sourceSecret {
-				isSensitive := false
-				for _, sensitiveKey := range sensitiveKeys {
-					if strings.EqualFold(k, sensitiveKey) ||
-						strings.Contains(strings.ToLower(k), strings.ToLower(sensitiveKey)) {
-						isSensitive = true
-						foundSensitiveKeys = true
-						break
-					}
-				}
-
-				if isSensitive {
+				if sensitiveRules.Contains(k) {
+					foundSensitiveKeys = true
 					sensitiveData[k] = v
 					splitKeysList = append(splitKeysList, k)
 				} else {
@@ -320,73 +238,39 @@ All split operations are logged to the specified log file (--log-to) in JSON for
 				fmt.Printf("Error: No keys in the source secret match any of the sensitive key patterns defined in the config.\n")
 				fmt.Printf("Source secret keys: %v\n", getKeysFromMap(sourceSecret))
 				fmt.Printf("Sensitive key patterns: %v\n", sensitiveKeys)
-				os.Exit(1)
+				exit(1)
 			}
 
 			fmt.Printf("Found %d sensitive keys to split: %s\n", len(sensitiveData), strings.Join(splitKeysList, ", "))
 
-			// Create target with sensitive keys and update source with non-sensitive keys
-			if storeType == "vault" {
-				vaultSourceClient := sourceClient.(*vault.Client)
-				vaultTargetClient := targetClient.(*vault.Client)
-
-				// Create target with sensitive keys
-				err = vaultTargetClient.WriteSecret(targetPath, sensitiveData)
-				if err != nil {
-					fmt.Printf("Error writing target secret: %v\n", err)
-					os.Exit(1)
+			// Create target with sensitive keys
+			targetSecret := store.NewSecret(sensitiveData)
+			for k := range sensitiveData {
+				targetSecret.Sensitive[k] = true
+				targetSecret.BinaryKeys[k] = secret.IsBinaryKey(k)
+				if targetSecret.BinaryKeys[k] && targetStore.Type() != storeType {
+					fmt.Printf("Error: Key %s holds binary data, which can only be split to a %s store\n", k, storeType)
+					exit(1)
 				}
+			}
+			err = targetStore.WriteSecret(targetPath, targetSecret)
+			if err != nil {
+				fmt.Printf("Error writing target secret: %v\n", err)
+				exit(1)
+			}
 
-				fmt.Printf("Successfully created target secret at %s with sensitive keys\n", targetPath)
-
-				// Update source with non-sensitive keys
-				err = vaultSourceClient.WriteSecret(sourcePath, newSourceData)
-				if err != nil {
-					fmt.Printf("Error updating source secret: %v\n", err)
-					fmt.Println("WARNING: Sensitive keys have been copied to the target but source was not updated!")
-					os.Exit(1)
-				}
-			} else if storeType == "awssecretsmanager" {
-				// Use the AWS Secrets Manager clients we already created
-				awsSourceClient := sourceClient.(*awssecretsmanager.Client)
-				awsTargetClient := targetClient.(*awssecretsmanager.Client)
-
-				// AWS requires two separate operations for the split
-				_, _, err = awsTargetClient.GetSecret(targetPath)
-				if err == nil {
-					fmt.Printf("Error: Target path %s already exists. Split operation requires a new target path.\n", targetPath)
-					os.Exit(1)
-				} else if !strings.Contains(err.Error(), "not found") {
-					fmt.Printf("Error checking target path: %v\n", err)
-					os.Exit(1)
-				}
-
-				// Create the target secret with sensitive data directly
-				targetOptions := awssecretsmanager.CopyOptions{
-					Overwrite: true,
-				}
-
-				// Create the target secret with sensitive data
-				err = awsTargetClient.CopySecretData(sensitiveData, targetPath, targetOptions, configs)
-				if err != nil {
-					fmt.Printf("Error creating target secret: %v\n", err)
-					os.Exit(1)
-				}
-
-				fmt.Printf("Successfully created target secret at %s with sensitive keys\n", targetPath)
-
-				// Update the source secret with only the non-sensitive data
-				sourceOptions := awssecretsmanager.CopyOptions{
-					Overwrite: true,
-					Prune:     true, // Ensure sensitive keys are removed
-				}
+			fmt.Printf("Successfully created target secret at %s with sensitive keys\n", targetPath)
 
-				err = awsSourceClient.CopySecretData(newSourceData, sourcePath, sourceOptions, configs)
-				if err != nil {
-					fmt.Printf("Error updating source secret: %v\n", err)
-					fmt.Println("WARNING: Sensitive keys have been copied to the target but source was not updated!")
-					os.Exit(1)
-				}
+			// Update source with non-sensitive keys, which removes the split keys
+			newSourceSecret := store.NewSecret(newSourceData)
+			for k := range newSourceData {
+				newSourceSecret.BinaryKeys[k] = secret.IsBinaryKey(k)
+			}
+			err = sourceStore.WriteSecret(sourcePath, newSourceSecret)
+			if err != nil {
+				fmt.Printf("Error updating source secret: %v\n", err)
+				fmt.Println("WARNING: Sensitive keys have been copied to the target but source was not updated!")
+				exit(1)
 			}
 
 			logSplitOperation(sourceEnv, sourcePath, targetPath, storeType, true,
@@ -3,10 +3,12 @@ module github.com/secretz/vault-promoter
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/aws/aws-sdk-go v1.55.7
 	github.com/hashicorp/vault/api v1.10.0
 	github.com/sergi/go-diff v1.3.1
 	github.com/spf13/cobra v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,94 @@
+package awsauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/transport"
+)
+
+// defaultRoleSessionName identifies the CLI in CloudTrail when no session name is configured
+const defaultRoleSessionName = "vault-promoter"
+
+// NewSession creates an AWS session and the client config that assumes the
+// environment's IAM role. Every AWS-backed store uses it so they all
+// authenticate the same way.
+func NewSession(envConfig *config.EnvironmentConfig) (*session.Session, *aws.Config, error) {
+	// Validate config
+	if envConfig.Role == "" && envConfig.Profile == "" && envConfig.Endpoint == "" {
+		return nil, nil, fmt.Errorf("AWS IAM role ARN or profile is required")
+	}
+
+	var duration time.Duration
+	if envConfig.SessionDuration != "" {
+		var err error
+		duration, err = time.ParseDuration(envConfig.SessionDuration)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid session duration %q: %w", envConfig.SessionDuration, err)
+		}
+	}
+
+	// Create AWS session from the profile, with the region and endpoint applied
+	// to every service, including STS for a local stand-in
+	options := session.Options{
+		Profile:           envConfig.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+		// Profiles that assume an MFA-protected role prompt for the code
+		AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+	}
+	httpClient, err := transport.NewHTTPClient(envConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to configure AWS transport: %w", err)
+	}
+	options.Config.HTTPClient = httpClient
+
+	if envConfig.Region != "" {
+		options.Config.Region = aws.String(envConfig.Region)
+	}
+	if envConfig.Endpoint != "" {
+		options.Config.Endpoint = aws.String(envConfig.Endpoint)
+	}
+
+	sess, err := session.NewSessionWithOptions(options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	// Without a role the profile's credentials are used as they are
+	if envConfig.Role == "" {
+		return sess, &aws.Config{}, nil
+	}
+
+	sessionName := envConfig.RoleSessionName
+	if sessionName == "" {
+		sessionName = defaultRoleSessionName
+	}
+
+	// Assume role, with a web identity token if one is configured
+	if envConfig.WebIdentityTokenFile != "" {
+		creds := stscreds.NewWebIdentityCredentials(sess, envConfig.Role, sessionName, envConfig.WebIdentityTokenFile)
+		return sess, &aws.Config{Credentials: creds}, nil
+	}
+
+	creds := stscreds.NewCredentials(sess, envConfig.Role, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = sessionName
+		if duration > 0 {
+			p.Duration = duration
+		}
+		if envConfig.ExternalID != "" {
+			p.ExternalID = aws.String(envConfig.ExternalID)
+		}
+		if envConfig.MFASerial != "" {
+			p.SerialNumber = aws.String(envConfig.MFASerial)
+			p.TokenProvider = stscreds.StdinTokenProvider
+		}
+	})
+
+	return sess, &aws.Config{
+		Credentials: creds,
+	}, nil
+}
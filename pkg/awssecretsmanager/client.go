@@ -1,352 +1,356 @@
 package awssecretsmanager
 
 import (
-	"encoding/json"
 	"fmt"
-	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/secretz/vault-promoter/pkg/awsauth"
 	"github.com/secretz/vault-promoter/pkg/config"
-	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/secretz/vault-promoter/pkg/store"
 )
 
-// Client handles interactions with AWS Secrets Manager
-type Client struct {
-	svc            *secretsmanager.SecretsManager
-	redactedKeys   []string
-	redactSecrets  bool // Default to true for AWS Secrets Manager
-	redactJSONVals bool
-}
+// Staging labels Secrets Manager moves between versions during a rotation
+const (
+	StageCurrent  = "AWSCURRENT"
+	StagePrevious = "AWSPREVIOUS"
+	StagePending  = "AWSPENDING"
+)
 
-// SecretDiff tracks changes between secret versions for auditing
-type SecretDiff struct {
-	Key        string
-	Current    string
-	Target     string
-	Diff       string
-	IsRedacted bool
-	Status     string // +, -, or * for added, removed, or modified
-}
+// versionIDPattern matches the version IDs Secrets Manager generates.
+// Any other version selector is taken as a staging label.
+var versionIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 
-// SecretComparison provides a structured view of differences for review
-type SecretComparison struct {
-	Path  string
-	Diffs []SecretDiff
+// Client handles interactions with AWS Secrets Manager
+type Client struct {
+	svc *secretsmanager.SecretsManager
 }
 
 // NewClient initializes connection with proper IAM role and settings
-func NewClient(envConfig *config.EnvironmentConfig, configs *config.Configs) (*Client, error) {
-	// Validate config
-	if envConfig.Role == "" {
-		return nil, fmt.Errorf("AWS IAM role ARN is required for AWS Secrets Manager")
-	}
-
-	// Create AWS session
-	sess, err := session.NewSession()
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	sess, awsConfig, err := awsauth.NewSession(envConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+		return nil, fmt.Errorf("failed to set up AWS Secrets Manager credentials: %w", err)
 	}
 
-	// Assume role if needed
-	creds := stscreds.NewCredentials(sess, envConfig.Role)
-
 	// Create Secrets Manager client
-	svc := secretsmanager.New(sess, &aws.Config{
-		Credentials: creds,
-	})
+	svc := secretsmanager.New(sess, awsConfig)
 
 	return &Client{
-		svc:            svc,
-		redactedKeys:   configs.GetRedactedKeys(),
-		redactSecrets:  configs.ShouldRedactSecrets(),
-		redactJSONVals: configs.ShouldRedactJSONValues(),
+		svc: svc,
 	}, nil
 }
 
-// GetSecret fetches and parses secret data with format detection
-func (c *Client) GetSecret(path string) (map[string]interface{}, bool, error) {
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreAWSSecretsManager
+}
+
+// isNotFoundError checks whether an AWS API error means the secret is missing
+func isNotFoundError(err error) bool {
+	return strings.Contains(err.Error(), secretsmanager.ErrCodeResourceNotFoundException)
+}
+
+// secretVersion splits a path such as "app/config@AWSPREVIOUS" into the secret
+// name and either a version ID or a staging label. Both are empty when the
+// path pins no version, which reads AWSCURRENT.
+func secretVersion(path string) (name, versionID, stage string) {
+	name, version := store.SplitVersion(path)
+	if versionIDPattern.MatchString(version) {
+		return name, version, ""
+	}
+	return name, "", version
+}
+
+// GetSecret fetches and parses secret data with format detection.
+// The path may select a version with "path@<version-id>" or "path@<staging-label>".
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	name, versionID, stage := secretVersion(path)
+
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+	if stage != "" {
+		input.VersionStage = aws.String(stage)
+	}
+
 	// Get the secret value
-	result, err := c.svc.GetSecretValue(&secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(path),
-	})
+	result, err := c.svc.GetSecretValue(input)
 
 	if err != nil {
 		// Check if the error is because the secret doesn't exist
-		if strings.Contains(err.Error(), "ResourceNotFoundException") {
-			return nil, false, fmt.Errorf("secret not found: %s", path)
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
 		}
-		return nil, false, fmt.Errorf("failed to get secret: %w", err)
+		return nil, fmt.Errorf("failed to get secret: %w", err)
 	}
 
-	var secretString string
+	var secret *store.Secret
 	if result.SecretString != nil {
-		secretString = *result.SecretString
+		secret = store.ParseSecretString(*result.SecretString)
 	} else {
-		return nil, false, fmt.Errorf("binary secrets not supported")
+		secret = store.NewBinarySecret(result.SecretBinary)
 	}
 
-	// Try to parse as JSON
-	var secretData map[string]interface{}
-	err = json.Unmarshal([]byte(secretString), &secretData)
-	if err != nil {
-		// Not a JSON object, return as a single value
-		return map[string]interface{}{
-			"value": secretString,
-		}, false, nil
-	}
+	// A binary or plain string secret is the secret itself
+	secret.MarkOpaqueSensitive()
 
-	// It's a valid JSON
-	return secretData, true, nil
+	return secret, nil
 }
 
-// CompareSecretPaths identifies differences for review before copying
-func (c *Client) CompareSecretPaths(sourcePath, targetPath string) (*SecretComparison, error) {
-	// Get the source secrets
-	sourceSecrets, sourceIsJSON, err := c.GetSecret(sourcePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get source secrets: %w", err)
-	}
-
-	// Get the target secrets
-	targetSecrets, targetIsJSON, err := c.GetSecret(targetPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get target secrets: %w", err)
-	}
-
-	// Check if both secrets are in the same format (JSON or non-JSON)
-	if sourceIsJSON != targetIsJSON {
-		return nil, fmt.Errorf("incompatible secret types: one is JSON, the other is a string")
-	}
+// SupportsBinary reports that Secrets Manager holds binary secrets natively
+func (c *Client) SupportsBinary() bool {
+	return true
+}
 
-	// Create a comparison object
-	comparison := &SecretComparison{
-		Path:  sourcePath,
-		Diffs: []SecretDiff{},
+// WriteSecret creates the secret or stores a new value for it.
+// Binary secrets are stored as SecretBinary, everything else as SecretString.
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	if _, version := store.SplitVersion(path); version != "" {
+		return fmt.Errorf("cannot write to a pinned version: %s", path)
 	}
 
-	// If both are simple strings, compare directly
-	if !sourceIsJSON && !targetIsJSON {
-		sourceValue := fmt.Sprintf("%v", sourceSecrets["value"])
-		targetValue := fmt.Sprintf("%v", targetSecrets["value"])
+	update := &secretsmanager.UpdateSecretInput{SecretId: aws.String(path)}
+	create := &secretsmanager.CreateSecretInput{Name: aws.String(path)}
 
-		// Skip if values are identical
-		if sourceValue == targetValue {
-			return comparison, nil
+	if secret.Binary {
+		content, err := secret.BinaryContent()
+		if err != nil {
+			return err
 		}
-
-		// Always redact for AWS Secrets Manager unless explicitly disabled
-		redacted := c.redactSecrets
-
-		// Generate diff only if not redacted
-		diffText := ""
-		if !redacted {
-			diffText = GenerateDiff(sourceValue, targetValue)
+		update.SecretBinary = content
+		create.SecretBinary = content
+	} else {
+		secretString, err := store.MarshalSecretString(secret)
+		if err != nil {
+			return err
 		}
-
-		comparison.Diffs = append(comparison.Diffs, SecretDiff{
-			Key:        "value",
-			Current:    sourceValue,
-			Target:     targetValue,
-			Diff:       diffText,
-			IsRedacted: redacted,
-			Status:     "*", // Modified value
-		})
-
-		return comparison, nil
+		update.SecretString = aws.String(secretString)
+		create.SecretString = aws.String(secretString)
 	}
 
-	// Track processed keys to avoid duplicates
-	processedKeys := make(map[string]bool)
+	_, err := c.svc.UpdateSecret(update)
+	if err != nil && isNotFoundError(err) {
+		_, err = c.svc.CreateSecret(create)
+	}
 
-	// Compare JSON objects
-	for key, currentValue := range sourceSecrets {
-		processedKeys[key] = true
+	if err != nil {
+		return fmt.Errorf("failed to write secret: %w", err)
+	}
 
-		// Convert value to string for comparison
-		currentValueStr := fmt.Sprintf("%v", currentValue)
+	return nil
+}
 
-		// Check if the key exists in target secrets
-		targetValue, exists := targetSecrets[key]
-		if !exists {
-			// Key only exists in current secrets (added)
-			// Check if the key should be redacted
-			redacted := c.isRedactedKey(key)
+// ListSecrets lists secret names under prefix, folding deeper names into "/"-suffixed folders
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+
+	input := &secretsmanager.ListSecretsInput{}
+	if prefix != "" {
+		input.Filters = []*secretsmanager.Filter{
+			{
+				Key:    aws.String("name"),
+				Values: []*string{aws.String(prefix)},
+			},
+		}
+	}
 
-			// Try to parse and redact JSON values if needed
-			if c.redactJSONVals {
-				redactedJSON, isJSON := c.TryParseAndRedactJSON(currentValueStr)
-				if isJSON {
-					currentValueStr = redactedJSON
+	seen := make(map[string]bool)
+	var names []string
+	err := c.svc.ListSecretsPages(input, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+		for _, entry := range page.SecretList {
+			name := aws.StringValue(entry.Name)
+
+			// The name filter is a plain prefix match, so keep only real children
+			rest := name
+			if prefix != "" {
+				if !strings.HasPrefix(name, prefix+"/") {
+					continue
 				}
+				rest = strings.TrimPrefix(name, prefix+"/")
 			}
 
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    currentValueStr,
-				Target:     "",
-				IsRedacted: redacted,
-				Status:     "+", // Added key
-			})
-		} else {
-			// Key exists in both, compare values
-			targetValueStr := fmt.Sprintf("%v", targetValue)
-
-			// Skip if values are identical
-			if currentValueStr == targetValueStr {
-				continue
+			if idx := strings.Index(rest, "/"); idx >= 0 {
+				name = strings.TrimPrefix(prefix+"/"+rest[:idx+1], "/")
 			}
 
-			// Check if the key should be redacted
-			redacted := c.isRedactedKey(key)
-
-			// Try to parse and redact JSON values if needed
-			if c.redactJSONVals {
-				redactedCurrentJSON, isCurrentJSON := c.TryParseAndRedactJSON(currentValueStr)
-				if isCurrentJSON {
-					currentValueStr = redactedCurrentJSON
-				}
-
-				redactedTargetJSON, isTargetJSON := c.TryParseAndRedactJSON(targetValueStr)
-				if isTargetJSON {
-					targetValueStr = redactedTargetJSON
-				}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
 			}
-
-			// Generate diff only if not redacted
-			diffText := ""
-			if !redacted {
-				diffText = GenerateDiff(currentValueStr, targetValueStr)
-			}
-
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    currentValueStr,
-				Target:     targetValueStr,
-				Diff:       diffText,
-				IsRedacted: redacted,
-				Status:     "*", // Modified value
-			})
 		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
 	}
 
-	// Find keys that only exist in target secrets (removed)
-	for key, targetValue := range targetSecrets {
-		if _, exists := processedKeys[key]; !exists {
-			// Key only exists in target secrets (removed)
-			targetValueStr := fmt.Sprintf("%v", targetValue)
-
-			// Check if the key should be redacted
-			redacted := c.isRedactedKey(key)
+	sort.Strings(names)
+	return names, nil
+}
 
-			// Try to parse and redact JSON values if needed
-			if c.redactJSONVals {
-				redactedJSON, isJSON := c.TryParseAndRedactJSON(targetValueStr)
-				if isJSON {
-					targetValueStr = redactedJSON
-				}
-			}
+// DeleteSecret schedules the secret for deletion using the default recovery window
+func (c *Client) DeleteSecret(path string) error {
+	if _, version := store.SplitVersion(path); version != "" {
+		return fmt.Errorf("cannot delete a single version: %s", path)
+	}
 
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    "",
-				Target:     targetValueStr,
-				IsRedacted: redacted,
-				Status:     "-", // Removed key
-			})
+	_, err := c.svc.DeleteSecret(&secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
 		}
+		return fmt.Errorf("failed to delete secret: %w", err)
 	}
 
-	return comparison, nil
+	return nil
 }
 
-// isRedactedKey determines which values need protection in logs and output
-func (c *Client) isRedactedKey(key string) bool {
-	// By default, all values in AWS Secrets Manager are considered secrets
-	if c.redactSecrets {
-		return true
+// SecretExists checks whether a live secret exists with the given name,
+// and holds the version when one is pinned
+func (c *Client) SecretExists(path string) (bool, error) {
+	if _, version := store.SplitVersion(path); version != "" {
+		_, err := c.GetMetadata(path)
+		if err != nil {
+			if store.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
 	}
 
-	// If redaction is disabled, check if this specific key should be redacted
-	key = strings.ToLower(key)
-	for _, redactedKey := range c.redactedKeys {
-		if strings.Contains(key, strings.ToLower(redactedKey)) {
-			return true
+	result, err := c.svc.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return false, nil
 		}
+		return false, fmt.Errorf("failed to describe secret: %w", err)
 	}
 
-	return false
+	// Secrets scheduled for deletion can't be read anymore
+	return result.DeletedDate == nil, nil
 }
 
-// IsJSONValue helps identify nested structures that need special handling
-func IsJSONValue(s string) bool {
-	var js interface{}
-	return json.Unmarshal([]byte(s), &js) == nil
-}
+// GetMetadata returns the current version and timestamps of the secret, or
+// those of the pinned version with its staging labels
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	name, versionID, stage := secretVersion(path)
+	if versionID != "" || stage != "" {
+		return c.versionMetadata(path, name, versionID, stage)
+	}
 
-// RedactJSONValues ensures sensitive data in nested structures is protected
-func (c *Client) RedactJSONValues(data interface{}) interface{} {
-	// Redact based on type
-	switch v := data.(type) {
-	case map[string]interface{}:
-		// Process each key in the map
-		result := make(map[string]interface{})
-		for key, value := range v {
-			// Check if this key should be redacted
-			if c.isRedactedKey(key) {
-				result[key] = "(redacted)"
-			} else {
-				// Recursively process nested values
-				result[key] = c.RedactJSONValues(value)
-			}
+	result, err := c.svc.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
 		}
-		return result
+		return nil, fmt.Errorf("failed to describe secret: %w", err)
+	}
 
-	case []interface{}:
-		// Process each item in the array
-		result := make([]interface{}, len(v))
-		for i, item := range v {
-			result[i] = c.RedactJSONValues(item)
-		}
-		return result
+	metadata := &store.Metadata{
+		Path:        path,
+		CreatedTime: aws.TimeValue(result.CreatedDate),
+		UpdatedTime: aws.TimeValue(result.LastChangedDate),
+		Labels:      make(map[string]string),
+	}
 
-	default:
-		// Return primitive values as is
-		return v
+	for versionID, stages := range result.VersionIdsToStages {
+		for _, stage := range stages {
+			if aws.StringValue(stage) == "AWSCURRENT" {
+				metadata.Version = versionID
+			}
+		}
 	}
-}
 
-// TryParseAndRedactJSON handles potential JSON strings that might contain sensitive data
-func (c *Client) TryParseAndRedactJSON(value string) (string, bool) {
-	if !IsJSONValue(value) {
-		return value, false
+	for _, tag := range result.Tags {
+		metadata.Labels[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
 
-	var data interface{}
-	err := json.Unmarshal([]byte(value), &data)
+	return metadata, nil
+}
+
+// versionMetadata finds the version selected by ID or staging label among the versions of the secret
+func (c *Client) versionMetadata(path, name, versionID, stage string) (*store.Metadata, error) {
+	var found *secretsmanager.SecretVersionsListEntry
+	err := c.svc.ListSecretVersionIdsPages(&secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          aws.String(name),
+		IncludeDeprecated: aws.Bool(true),
+	}, func(page *secretsmanager.ListSecretVersionIdsOutput, lastPage bool) bool {
+		for _, entry := range page.Versions {
+			if versionID != "" && aws.StringValue(entry.VersionId) == versionID {
+				found = entry
+				return false
+			}
+			for _, label := range entry.VersionStages {
+				if stage != "" && aws.StringValue(label) == stage {
+					found = entry
+					return false
+				}
+			}
+		}
+		return true
+	})
 	if err != nil {
-		return value, false
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to list secret versions: %w", err)
 	}
 
-	redactedData := c.RedactJSONValues(data)
-	if reflect.DeepEqual(data, redactedData) {
-		return value, false
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
 	}
 
-	redactedJSON, err := json.MarshalIndent(redactedData, "", "  ")
+	return &store.Metadata{
+		Path:        path,
+		Version:     aws.StringValue(found.VersionId),
+		CreatedTime: aws.TimeValue(found.CreatedDate),
+		UpdatedTime: aws.TimeValue(found.CreatedDate),
+		Labels:      map[string]string{"stages": strings.Join(aws.StringValueSlice(found.VersionStages), ",")},
+	}, nil
+}
+
+// ListVersions returns the versions Secrets Manager still keeps for the secret, oldest first.
+// Secrets Manager doesn't record who wrote a version, so Author is left empty.
+func (c *Client) ListVersions(path string) ([]store.VersionInfo, error) {
+	name, _ := store.SplitVersion(path)
+
+	var versions []store.VersionInfo
+	err := c.svc.ListSecretVersionIdsPages(&secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          aws.String(name),
+		IncludeDeprecated: aws.Bool(true),
+	}, func(page *secretsmanager.ListSecretVersionIdsOutput, lastPage bool) bool {
+		for _, entry := range page.Versions {
+			versions = append(versions, store.VersionInfo{
+				Version:     aws.StringValue(entry.VersionId),
+				CreatedTime: aws.TimeValue(entry.CreatedDate),
+			})
+		}
+		return true
+	})
 	if err != nil {
-		return value, false
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to list secret versions: %w", err)
 	}
 
-	return string(redactedJSON), true
-}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].CreatedTime.Before(versions[j].CreatedTime)
+	})
 
-// GenerateDiff provides visual representation of changes for review
-func GenerateDiff(current, target string) string {
-	dmp := diffmatchpatch.New()
-	diffs := dmp.DiffMain(current, target, false)
-	return dmp.DiffPrettyText(diffs)
+	return versions, nil
 }
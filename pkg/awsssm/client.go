@@ -0,0 +1,386 @@
+package awsssm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/secretz/vault-promoter/pkg/awsauth"
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// deleteBatchSize is the maximum number of parameters DeleteParameters accepts
+const deleteBatchSize = 10
+
+// Client handles interactions with AWS Systems Manager Parameter Store.
+// A secret path is a parameter hierarchy: each parameter directly under
+// the path is one key of the secret.
+type Client struct {
+	svc *ssm.SSM
+}
+
+// NewClient initializes connection with the same IAM role flow as Secrets Manager
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	sess, awsConfig, err := awsauth.NewSession(envConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up AWS SSM credentials: %w", err)
+	}
+
+	return &Client{
+		svc: ssm.New(sess, awsConfig),
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreAWSSSM
+}
+
+// isNotFoundError checks whether an AWS API error means the parameter is missing
+func isNotFoundError(err error) bool {
+	return strings.Contains(err.Error(), ssm.ErrCodeParameterNotFound)
+}
+
+// parameterPath normalizes a store path into a parameter hierarchy path
+func parameterPath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
+
+// getParameters returns the parameters directly under path, decrypted
+func (c *Client) getParameters(path string) ([]*ssm.Parameter, error) {
+	var parameters []*ssm.Parameter
+	err := c.svc.GetParametersByPathPages(&ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(false),
+		WithDecryption: aws.Bool(true),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		parameters = append(parameters, page.Parameters...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parameters: %w", err)
+	}
+
+	return parameters, nil
+}
+
+// GetSecret reads the parameters under path as keys. When path is itself a
+// single parameter, it is returned as a non-JSON secret like a plain string
+// in Secrets Manager.
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	prefix := parameterPath(path)
+
+	parameters, err := c.getParameters(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parameters) == 0 {
+		result, err := c.svc.GetParameter(&ssm.GetParameterInput{
+			Name:           aws.String(prefix),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			if isNotFoundError(err) {
+				return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+			}
+			return nil, fmt.Errorf("failed to get parameter: %w", err)
+		}
+
+		secret := store.NewSecret(map[string]interface{}{"value": aws.StringValue(result.Parameter.Value)})
+		secret.IsJSON = false
+		secret.Sensitive["value"] = aws.StringValue(result.Parameter.Type) == ssm.ParameterTypeSecureString
+		return secret, nil
+	}
+
+	secret := store.NewSecret(nil)
+	for _, parameter := range parameters {
+		key := strings.TrimPrefix(aws.StringValue(parameter.Name), prefix+"/")
+		secret.Data[key] = aws.StringValue(parameter.Value)
+
+		// SecureString parameters are secret whatever their name
+		if aws.StringValue(parameter.Type) == ssm.ParameterTypeSecureString {
+			secret.Sensitive[key] = true
+		}
+	}
+
+	return secret, nil
+}
+
+// WriteSecret writes one parameter per key and removes parameters whose key is gone.
+// Existing parameters keep their type; new ones are SecureString when the key is
+// sensitive and String otherwise.
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	prefix := parameterPath(path)
+
+	// SSM rejects empty values, so check them all before the first write
+	// rather than leave the secret half written
+	if err := checkValues(prefix, secret); err != nil {
+		return err
+	}
+
+	if !secret.IsJSON {
+		parameterType := ssm.ParameterTypeString
+		if secret.IsSensitive("value") {
+			parameterType = ssm.ParameterTypeSecureString
+		}
+		return c.putParameter(prefix, store.StringValue(secret.Data["value"]), parameterType)
+	}
+
+	existing, err := c.getParameters(prefix)
+	if err != nil {
+		return err
+	}
+
+	existingTypes := make(map[string]string)
+	for _, parameter := range existing {
+		key := strings.TrimPrefix(aws.StringValue(parameter.Name), prefix+"/")
+		existingTypes[key] = aws.StringValue(parameter.Type)
+	}
+
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		parameterType, exists := existingTypes[key]
+		if !exists {
+			parameterType = ssm.ParameterTypeString
+			if secret.IsSensitive(key) {
+				parameterType = ssm.ParameterTypeSecureString
+			}
+		}
+
+		if err := c.putParameter(prefix+"/"+key, store.StringValue(secret.Data[key]), parameterType); err != nil {
+			return err
+		}
+	}
+
+	var removed []string
+	for key := range existingTypes {
+		if _, ok := secret.Data[key]; !ok {
+			removed = append(removed, prefix+"/"+key)
+		}
+	}
+
+	return c.deleteParameters(removed)
+}
+
+// checkValues fails when any value of the secret can't be stored as a parameter
+func checkValues(prefix string, secret *store.Secret) error {
+	if !secret.IsJSON {
+		if store.StringValue(secret.Data["value"]) == "" {
+			return fmt.Errorf("cannot write parameter %s: SSM parameters can't be empty", prefix)
+		}
+		return nil
+	}
+
+	var empty []string
+	for key, value := range secret.Data {
+		if store.StringValue(value) == "" {
+			empty = append(empty, key)
+		}
+	}
+	if len(empty) > 0 {
+		sort.Strings(empty)
+		return fmt.Errorf("cannot write %s: SSM parameters can't be empty, but %s would be",
+			prefix, strings.Join(empty, ", "))
+	}
+
+	return nil
+}
+
+// putParameter creates or overwrites a single parameter
+func (c *Client) putParameter(name, value, parameterType string) error {
+	_, err := c.svc.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(value),
+		Type:      aws.String(parameterType),
+		Overwrite: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write parameter %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// deleteParameters deletes the named parameters in batches
+func (c *Client) deleteParameters(names []string) error {
+	sort.Strings(names)
+	for start := 0; start < len(names); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+
+		_, err := c.svc.DeleteParameters(&ssm.DeleteParametersInput{
+			Names: aws.StringSlice(names[start:end]),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete parameters: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// describeParameters returns the metadata of the parameters below path.
+// The option is "OneLevel" for direct children or "Recursive" for any depth.
+func (c *Client) describeParameters(path, option string) ([]*ssm.ParameterMetadata, error) {
+	var parameters []*ssm.ParameterMetadata
+	err := c.svc.DescribeParametersPages(&ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{
+			{
+				Key:    aws.String("Path"),
+				Option: aws.String(option),
+				Values: []*string{aws.String(path)},
+			},
+		},
+	}, func(page *ssm.DescribeParametersOutput, lastPage bool) bool {
+		parameters = append(parameters, page.Parameters...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe parameters: %w", err)
+	}
+
+	return parameters, nil
+}
+
+// ListSecrets lists the hierarchies under prefix. A hierarchy holding parameters
+// is listed as a secret, one holding deeper hierarchies as a "/"-suffixed folder.
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	root := parameterPath(prefix)
+
+	parameters, err := c.describeParameters(root, "Recursive")
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.Trim(prefix, "/")
+	if base != "" {
+		base += "/"
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, parameter := range parameters {
+		rest := strings.TrimPrefix(strings.TrimPrefix(aws.StringValue(parameter.Name), root), "/")
+
+		segments := strings.Split(rest, "/")
+		if len(segments) < 2 {
+			// A parameter directly under prefix is a key of prefix itself
+			continue
+		}
+
+		name := base + segments[0]
+		if len(segments) > 2 {
+			name += "/"
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeleteSecret deletes every parameter directly under path
+func (c *Client) DeleteSecret(path string) error {
+	prefix := parameterPath(path)
+
+	parameters, err := c.describeParameters(prefix, "OneLevel")
+	if err != nil {
+		return err
+	}
+
+	if len(parameters) == 0 {
+		// The path may be a single parameter
+		_, err := c.svc.DeleteParameter(&ssm.DeleteParameterInput{Name: aws.String(prefix)})
+		if err != nil {
+			if isNotFoundError(err) {
+				return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+			}
+			return fmt.Errorf("failed to delete parameter: %w", err)
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(parameters))
+	for _, parameter := range parameters {
+		names = append(names, aws.StringValue(parameter.Name))
+	}
+
+	return c.deleteParameters(names)
+}
+
+// SecretExists checks whether any parameter is stored at or directly under path
+func (c *Client) SecretExists(path string) (bool, error) {
+	_, err := c.GetMetadata(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetMetadata returns the last modification of the parameters under path.
+// Parameters are versioned one by one, so the version is the highest of them.
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	prefix := parameterPath(path)
+
+	parameters, err := c.describeParameters(prefix, "OneLevel")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parameters) == 0 {
+		result, err := c.svc.GetParameter(&ssm.GetParameterInput{Name: aws.String(prefix)})
+		if err != nil {
+			if isNotFoundError(err) {
+				return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+			}
+			return nil, fmt.Errorf("failed to get parameter: %w", err)
+		}
+
+		return &store.Metadata{
+			Path:        path,
+			Version:     fmt.Sprintf("%d", aws.Int64Value(result.Parameter.Version)),
+			UpdatedTime: aws.TimeValue(result.Parameter.LastModifiedDate),
+			Labels:      map[string]string{"type": aws.StringValue(result.Parameter.Type)},
+		}, nil
+	}
+
+	metadata := &store.Metadata{
+		Path:   path,
+		Labels: make(map[string]string),
+	}
+
+	var version int64
+	for _, parameter := range parameters {
+		if aws.Int64Value(parameter.Version) > version {
+			version = aws.Int64Value(parameter.Version)
+		}
+
+		modified := aws.TimeValue(parameter.LastModifiedDate)
+		if modified.After(metadata.UpdatedTime) {
+			metadata.UpdatedTime = modified
+			metadata.Labels["last_modified_user"] = aws.StringValue(parameter.LastModifiedUser)
+		}
+	}
+	metadata.Version = fmt.Sprintf("%d", version)
+
+	return metadata, nil
+}
@@ -0,0 +1,72 @@
+package azurekeyvault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAuthorityHost is the Microsoft Entra ID endpoint of the public Azure cloud
+	defaultAuthorityHost = "https://login.microsoftonline.com"
+
+	// keyVaultScope requests a token for the Key Vault data plane
+	keyVaultScope = "https://vault.azure.net/.default"
+)
+
+// clientCredentials obtains and caches access tokens using the OAuth2 client credentials grant
+type clientCredentials struct {
+	authorityHost string
+	tenantID      string
+	clientID      string
+	clientSecret  string
+	httpClient    *http.Client
+
+	token     string
+	expiresAt time.Time
+}
+
+// tokenResponse is the subset of the token endpoint response we use
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Token returns a valid access token, requesting a new one when the cached one is about to expire
+func (c *clientCredentials) Token() (string, error) {
+	if c.token != "" && time.Now().Add(time.Minute).Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimRight(c.authorityHost, "/"), url.PathEscape(c.tenantID))
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.clientID},
+		"client_secret": {c.clientSecret},
+		"scope":         {keyVaultScope},
+	}
+
+	resp, err := c.httpClient.PostForm(tokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to request Azure access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Azure token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		return "", fmt.Errorf("failed to authenticate with Azure (status %d): %s %s", resp.StatusCode, result.Error, result.Description)
+	}
+
+	c.token = result.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return c.token, nil
+}
@@ -0,0 +1,334 @@
+package azurekeyvault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// apiVersion is the Key Vault REST API version used for all requests
+const apiVersion = "7.4"
+
+// pathSeparator replaces "/" in paths, since Key Vault secret names only allow letters, digits and dashes
+const pathSeparator = "--"
+
+// validSecretName matches the names Key Vault accepts
+var validSecretName = regexp.MustCompile(`^[0-9a-zA-Z-]{1,127}$`)
+
+// Client handles interactions with the Azure Key Vault REST API
+type Client struct {
+	vaultURI    string
+	httpClient  *http.Client
+	credentials *clientCredentials
+}
+
+// secretBundle is a secret as returned by the Key Vault API
+type secretBundle struct {
+	ID          string            `json:"id"`
+	Value       string            `json:"value"`
+	ContentType string            `json:"contentType"`
+	Attributes  secretAttributes  `json:"attributes"`
+	Tags        map[string]string `json:"tags"`
+}
+
+// secretAttributes holds the timestamps of a secret version, in Unix seconds
+type secretAttributes struct {
+	Created int64 `json:"created"`
+	Updated int64 `json:"updated"`
+}
+
+// secretSetParameters is the body of a request that stores a new secret version
+type secretSetParameters struct {
+	Value       string `json:"value"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// secretListResult is one page of the secret listing
+type secretListResult struct {
+	Value    []secretBundle `json:"value"`
+	NextLink string         `json:"nextLink"`
+}
+
+// errorResponse is the error body returned by the Key Vault API
+type errorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewClient creates a Key Vault client authenticated with the environment's client credentials
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	if envConfig.VaultURI == "" {
+		return nil, fmt.Errorf("vault_uri is required for Azure Key Vault")
+	}
+
+	if _, err := url.Parse(envConfig.VaultURI); err != nil {
+		return nil, fmt.Errorf("invalid vault_uri %s: %w", envConfig.VaultURI, err)
+	}
+
+	clientSecret, err := envConfig.GetAzureClientSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure client secret: %w", err)
+	}
+
+	authorityHost := envConfig.AuthorityHost
+	if authorityHost == "" {
+		authorityHost = defaultAuthorityHost
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
+	return &Client{
+		vaultURI:   strings.TrimRight(envConfig.VaultURI, "/"),
+		httpClient: httpClient,
+		credentials: &clientCredentials{
+			authorityHost: authorityHost,
+			tenantID:      envConfig.TenantID,
+			clientID:      envConfig.ClientID,
+			clientSecret:  clientSecret,
+			httpClient:    httpClient,
+		},
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreAzureKeyVault
+}
+
+// secretName converts a store path into a Key Vault secret name
+func secretName(path string) (string, error) {
+	name := strings.ReplaceAll(strings.Trim(path, "/"), "/", pathSeparator)
+	if !validSecretName.MatchString(name) {
+		return "", fmt.Errorf("invalid Azure Key Vault secret name %q: only letters, digits and dashes are allowed", name)
+	}
+	return name, nil
+}
+
+// secretPath converts a Key Vault secret name back into a store path
+func secretPath(name string) string {
+	return strings.ReplaceAll(name, pathSeparator, "/")
+}
+
+// do sends an authenticated request and decodes the JSON response into out.
+// A 404 response is reported as store.ErrSecretNotFound.
+func (c *Client) do(method, requestURL string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	token, err := c.credentials.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to Azure Key Vault failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return store.ErrSecretNotFound
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("azure key vault returned status %d: %s %s", resp.StatusCode, apiErr.Error.Code, apiErr.Error.Message)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode Azure Key Vault response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// secretURL builds the URL of the current version of a secret
+func (c *Client) secretURL(name string) string {
+	return c.vaultURI + "/secrets/" + url.PathEscape(name) + "?api-version=" + apiVersion
+}
+
+// getBundle fetches the current version of a secret
+func (c *Client) getBundle(path string) (*secretBundle, error) {
+	name, err := secretName(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle secretBundle
+	if err := c.do(http.MethodGet, c.secretURL(name), nil, &bundle); err != nil {
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	return &bundle, nil
+}
+
+// GetSecret fetches and parses secret data with format detection
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	bundle, err := c.getBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A plain string secret is the secret itself
+	secret := store.ParseSecretString(bundle.Value)
+	secret.MarkOpaqueSensitive()
+
+	return secret, nil
+}
+
+// WriteSecret stores a new version of the secret, creating it if needed
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	name, err := secretName(path)
+	if err != nil {
+		return err
+	}
+
+	value, err := store.MarshalSecretString(secret)
+	if err != nil {
+		return err
+	}
+
+	request := secretSetParameters{Value: value}
+	if secret.IsJSON {
+		request.ContentType = "application/json"
+	}
+
+	if err := c.do(http.MethodPut, c.secretURL(name), request, nil); err != nil {
+		return fmt.Errorf("failed to write secret: %w", err)
+	}
+
+	return nil
+}
+
+// ListSecrets lists secret paths under prefix, folding deeper names into "/"-suffixed folders
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+
+	seen := make(map[string]bool)
+	var names []string
+
+	nextLink := c.vaultURI + "/secrets?api-version=" + apiVersion
+	for nextLink != "" {
+		var page secretListResult
+		if err := c.do(http.MethodGet, nextLink, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list secrets: %w", err)
+		}
+
+		for _, entry := range page.Value {
+			// The id ends with the secret name
+			name := secretPath(entry.ID[strings.LastIndex(entry.ID, "/")+1:])
+
+			rest := name
+			if prefix != "" {
+				if !strings.HasPrefix(name, prefix+"/") {
+					continue
+				}
+				rest = strings.TrimPrefix(name, prefix+"/")
+			}
+
+			if idx := strings.Index(rest, "/"); idx >= 0 {
+				name = strings.TrimPrefix(prefix+"/"+rest[:idx+1], "/")
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+
+		nextLink = page.NextLink
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeleteSecret deletes the secret; vaults with soft-delete keep it recoverable
+func (c *Client) DeleteSecret(path string) error {
+	name, err := secretName(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.do(http.MethodDelete, c.secretURL(name), nil, nil); err != nil {
+		if store.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+
+	return nil
+}
+
+// SecretExists checks whether a secret exists with the given path
+func (c *Client) SecretExists(path string) (bool, error) {
+	_, err := c.getBundle(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetMetadata returns the current version, timestamps and tags of the secret
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	bundle, err := c.getBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &store.Metadata{
+		Path:    path,
+		Version: bundle.ID[strings.LastIndex(bundle.ID, "/")+1:],
+		Labels:  make(map[string]string),
+	}
+
+	if bundle.Attributes.Created != 0 {
+		metadata.CreatedTime = time.Unix(bundle.Attributes.Created, 0)
+	}
+	if bundle.Attributes.Updated != 0 {
+		metadata.UpdatedTime = time.Unix(bundle.Attributes.Updated, 0)
+	}
+
+	for key, value := range bundle.Tags {
+		metadata.Labels[key] = value
+	}
+
+	return metadata, nil
+}
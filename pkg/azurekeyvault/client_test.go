@@ -0,0 +1,269 @@
+package azurekeyvault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// fakeKeyVault serves the parts of the Key Vault and token APIs the client uses
+type fakeKeyVault struct {
+	t        *testing.T
+	server   *httptest.Server
+	mu       sync.Mutex
+	secrets  map[string]secretBundle
+	pageSize int
+	tokens   int
+}
+
+func newFakeKeyVault(t *testing.T) *fakeKeyVault {
+	f := &fakeKeyVault{t: t, secrets: make(map[string]secretBundle), pageSize: 2}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeKeyVault) client() *Client {
+	return &Client{
+		vaultURI:   f.server.URL,
+		httpClient: f.server.Client(),
+		credentials: &clientCredentials{
+			authorityHost: f.server.URL,
+			tenantID:      "tenant",
+			clientID:      "client",
+			clientSecret:  "secret",
+			httpClient:    f.server.Client(),
+		},
+	}
+}
+
+func (f *fakeKeyVault) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+		if r.FormValue("client_secret") != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.tokens++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Query().Get("api-version") != apiVersion {
+		f.t.Errorf("request %s %s without api-version %s", r.Method, r.URL, apiVersion)
+	}
+
+	if r.URL.Path == "/secrets" {
+		f.list(w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/secrets/")
+	switch r.Method {
+	case http.MethodGet:
+		bundle, ok := f.secrets[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "SecretNotFound"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(bundle)
+	case http.MethodPut:
+		var params secretSetParameters
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			f.t.Errorf("failed to decode PUT body: %v", err)
+		}
+		bundle := secretBundle{
+			ID:          f.server.URL + "/secrets/" + name + "/v2",
+			Value:       params.Value,
+			ContentType: params.ContentType,
+			Attributes:  secretAttributes{Created: 1700000000, Updated: 1700000100},
+		}
+		f.secrets[name] = bundle
+		_ = json.NewEncoder(w).Encode(bundle)
+	case http.MethodDelete:
+		if _, ok := f.secrets[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.secrets, name)
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns the secret ids in pages of pageSize, linked with nextLink
+func (f *fakeKeyVault) list(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	for name := range f.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	start := 0
+	if skip := r.URL.Query().Get("skip"); skip != "" {
+		for start < len(names) && names[start] != skip {
+			start++
+		}
+	}
+
+	var page secretListResult
+	for i := start; i < len(names) && i < start+f.pageSize; i++ {
+		page.Value = append(page.Value, secretBundle{ID: f.server.URL + "/secrets/" + names[i]})
+	}
+	if start+f.pageSize < len(names) {
+		page.NextLink = f.server.URL + "/secrets?api-version=" + apiVersion + "&skip=" + names[start+f.pageSize]
+	}
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func TestWriteAndGetSecret(t *testing.T) {
+	fake := newFakeKeyVault(t)
+	client := fake.client()
+
+	secret := store.NewSecret(map[string]interface{}{"username": "app", "password": "hunter2"})
+	if err := client.WriteSecret("app/dev/db", secret); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	stored, ok := fake.secrets["app--dev--db"]
+	if !ok {
+		t.Fatalf("secret not stored under the mapped name, have %v", fake.secrets)
+	}
+	if stored.ContentType != "application/json" {
+		t.Errorf("content type = %q, want application/json", stored.ContentType)
+	}
+
+	got, err := client.GetSecret("app/dev/db")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if !got.IsJSON || !reflect.DeepEqual(got.Data, secret.Data) {
+		t.Errorf("GetSecret() = %v (json %v), want %v", got.Data, got.IsJSON, secret.Data)
+	}
+	if got.IsSensitive("password") {
+		t.Errorf("keys of a JSON secret should be left to the key rules")
+	}
+
+	if fake.tokens != 1 {
+		t.Errorf("requested %d tokens, want the first one to be reused", fake.tokens)
+	}
+}
+
+func TestPlainStringSecret(t *testing.T) {
+	fake := newFakeKeyVault(t)
+	client := fake.client()
+
+	fake.secrets["api-key"] = secretBundle{ID: fake.server.URL + "/secrets/api-key/v1", Value: "s3cr3t"}
+
+	got, err := client.GetSecret("api-key")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if got.IsJSON || got.Data["value"] != "s3cr3t" || !got.IsSensitive("value") {
+		t.Errorf("GetSecret() = %v (json %v, sensitive %v), want the sensitive plain value", got.Data, got.IsJSON, got.Sensitive)
+	}
+
+	if err := client.WriteSecret("api-key", got); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if stored := fake.secrets["api-key"]; stored.Value != "s3cr3t" || stored.ContentType != "" {
+		t.Errorf("stored %q with content type %q, want the plain value", stored.Value, stored.ContentType)
+	}
+}
+
+func TestGetSecretNotFound(t *testing.T) {
+	client := newFakeKeyVault(t).client()
+
+	_, err := client.GetSecret("app/missing")
+	if !store.IsNotFound(err) {
+		t.Fatalf("GetSecret() error = %v, want not found", err)
+	}
+
+	exists, err := client.SecretExists("app/missing")
+	if err != nil || exists {
+		t.Errorf("SecretExists() = %v, %v, want false", exists, err)
+	}
+
+	if err := client.DeleteSecret("app/missing"); !store.IsNotFound(err) {
+		t.Errorf("DeleteSecret() error = %v, want not found", err)
+	}
+}
+
+func TestInvalidSecretName(t *testing.T) {
+	client := newFakeKeyVault(t).client()
+
+	for _, path := range []string{"app/db_config", "app/dev.db", strings.Repeat("a", 128)} {
+		if _, err := client.GetSecret(path); err == nil || store.IsNotFound(err) {
+			t.Errorf("GetSecret(%q) error = %v, want an invalid name error", path, err)
+		}
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	fake := newFakeKeyVault(t)
+	client := fake.client()
+
+	for _, name := range []string{"app--dev--db", "app--dev--api", "app--prod--db", "app--shared", "other"} {
+		fake.secrets[name] = secretBundle{ID: fake.server.URL + "/secrets/" + name}
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"app/", "other"}},
+		{prefix: "app", want: []string{"app/dev/", "app/prod/", "app/shared"}},
+		{prefix: "/app/dev/", want: []string{"app/dev/api", "app/dev/db"}},
+		{prefix: "missing", want: nil},
+	}
+
+	for _, tt := range tests {
+		got, err := client.ListSecrets(tt.prefix)
+		if err != nil {
+			t.Fatalf("ListSecrets(%q) error = %v", tt.prefix, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListSecrets(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestGetMetadata(t *testing.T) {
+	fake := newFakeKeyVault(t)
+	client := fake.client()
+
+	fake.secrets["app--db"] = secretBundle{
+		ID:         fake.server.URL + "/secrets/app--db/abc123",
+		Attributes: secretAttributes{Created: 1700000000, Updated: 1700000100},
+		Tags:       map[string]string{"team": "payments"},
+	}
+
+	metadata, err := client.GetMetadata("app/db")
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if metadata.Version != "abc123" {
+		t.Errorf("Version = %q, want abc123", metadata.Version)
+	}
+	if metadata.CreatedTime.Unix() != 1700000000 || metadata.UpdatedTime.Unix() != 1700000100 {
+		t.Errorf("times = %v, %v", metadata.CreatedTime, metadata.UpdatedTime)
+	}
+	if metadata.Labels["team"] != "payments" {
+		t.Errorf("Labels = %v, want the secret tags", metadata.Labels)
+	}
+}
@@ -0,0 +1,95 @@
+package comparison
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// BlameResult tells, for each key of the latest readable version of a secret,
+// the version that introduced its current value
+type BlameResult struct {
+	Path      string
+	Instance  string
+	StoreType string
+	Version   string // latest readable version the blame is computed for
+	Lines     []BlameLine
+}
+
+// BlameLine is the origin of the current value of one key
+type BlameLine struct {
+	Key         string
+	Version     string
+	CreatedTime time.Time
+	Author      string // empty when the store doesn't record who wrote a version
+	Value       string
+	IsRedacted  bool
+}
+
+// BlameSecret walks the versions of a secret, oldest first, and records for each
+// key the version where it got its current value. Versions that can't be read
+// anymore are skipped, so a value may have been introduced by a deleted version
+// just before the one reported.
+func BlameSecret(instanceName, path, env, kvEngine string, configs *config.Configs) (*BlameResult, error) {
+	secretStore, versions, err := listVersions(instanceName, path, env, kvEngine, configs)
+	if err != nil {
+		return nil, err
+	}
+	defer CloseStore(secretStore)
+
+	result := &BlameResult{
+		Path:      path,
+		Instance:  instanceName,
+		StoreType: secretStore.Type(),
+	}
+
+	origins := make(map[string]store.VersionInfo)
+	var previous, current *store.Secret
+	for _, version := range versions {
+		if version.Deleted {
+			continue
+		}
+
+		secret, err := readVersion(secretStore, path, version.Version)
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			continue
+		}
+
+		for key, value := range secret.Data {
+			if previous != nil {
+				if oldValue, existed := previous.Data[key]; existed && store.StringValue(oldValue) == store.StringValue(value) {
+					continue
+				}
+			}
+			origins[key] = version
+		}
+
+		previous = secret
+		current = secret
+		result.Version = version.Version
+	}
+
+	if current == nil {
+		return nil, fmt.Errorf("%w: no readable version of %s", store.ErrSecretNotFound, path)
+	}
+
+	redaction := configs.RedactionFor(instanceName, path)
+	for _, key := range sortedKeys(current.Data) {
+		origin := origins[key]
+		result.Lines = append(result.Lines, BlameLine{
+			Key:         key,
+			Version:     origin.Version,
+			CreatedTime: origin.CreatedTime,
+			Author:      origin.Author,
+			Value:       secretValue(current, key, redaction),
+			IsRedacted:  shouldRedact(key, current, redaction) && !current.Binary,
+		})
+	}
+
+	return result, nil
+}
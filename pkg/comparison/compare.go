@@ -0,0 +1,314 @@
+package comparison
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ComparisonResult holds the result of comparing a secret between two stores
+type ComparisonResult struct {
+	SourcePath      string
+	TargetPath      string
+	SourceEnv       string
+	TargetEnv       string
+	SourceInstance  string
+	TargetInstance  string
+	SourceStoreType string
+	TargetStoreType string
+	SourceKVEngine  string
+	TargetKVEngine  string
+	Comparisons     []*ComparisonItem
+	MissingInSource []string
+	MissingInTarget []string
+}
+
+// ComparisonItem represents a comparison between two secrets
+type ComparisonItem struct {
+	Path  string
+	Diffs []DiffItem
+}
+
+// DiffItem represents a difference between two secrets
+type DiffItem struct {
+	Key        string
+	Current    string
+	Target     string
+	Diff       string
+	IsRedacted bool
+	Status     string // +, -, or * for added, removed, or modified
+}
+
+// CompareStores compares a secret between two configured instances.
+// The instances can be of any store type, including the same instance
+// when two paths of one store are compared.
+func CompareStores(
+	sourceInstanceName, targetInstanceName, sourcePath, targetPath string,
+	sourceEnv, targetEnv, sourceKV, targetKV string,
+	configs *config.Configs,
+) (*ComparisonResult, error) {
+	sourceStore, err := NewStore(sourceInstanceName, configs, StoreOptions{Env: sourceEnv, KVEngine: sourceKV})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source store: %w", err)
+	}
+	defer CloseStore(sourceStore)
+
+	targetStore, err := NewStore(targetInstanceName, configs, StoreOptions{Env: targetEnv, KVEngine: targetKV})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open target store: %w", err)
+	}
+	defer CloseStore(targetStore)
+
+	// Initialize result
+	result := &ComparisonResult{
+		SourcePath:      sourcePath,
+		TargetPath:      targetPath,
+		SourceEnv:       sourceEnv,
+		TargetEnv:       targetEnv,
+		SourceInstance:  sourceInstanceName,
+		TargetInstance:  targetInstanceName,
+		SourceStoreType: sourceStore.Type(),
+		TargetStoreType: targetStore.Type(),
+		SourceKVEngine:  sourceKV,
+		TargetKVEngine:  targetKV,
+	}
+
+	if err := checkAccess(sourceStore, sourcePath, false); err != nil {
+		return nil, fmt.Errorf("cannot read source secret: %w", err)
+	}
+
+	if err := checkAccess(targetStore, targetPath, false); err != nil {
+		return nil, fmt.Errorf("cannot read target secret: %w", err)
+	}
+
+	sourceSecret, err := getSecretIfExists(sourceStore, sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source secrets: %w", err)
+	}
+
+	targetSecret, err := getSecretIfExists(targetStore, targetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target secrets: %w", err)
+	}
+
+	// If neither exists, return an error
+	if sourceSecret == nil && targetSecret == nil {
+		return nil, fmt.Errorf("secrets don't exist in both stores at paths %s and %s", sourcePath, targetPath)
+	}
+
+	if sourceSecret == nil {
+		result.MissingInSource = append(result.MissingInSource, sourcePath)
+	}
+	if targetSecret == nil {
+		result.MissingInTarget = append(result.MissingInTarget, targetPath)
+	}
+
+	comparison := compareSecrets(
+		sourcePath, sourceSecret, targetSecret,
+		fmt.Sprintf("%s (%s)", sourceInstanceName, sourceStore.Type()),
+		fmt.Sprintf("%s (%s)", targetInstanceName, targetStore.Type()),
+		configs.RedactionFor(sourceInstanceName, sourcePath),
+		configs.RedactionFor(targetInstanceName, targetPath),
+	)
+
+	// Only add the comparison if there are differences
+	if len(comparison.Diffs) > 0 {
+		result.Comparisons = append(result.Comparisons, comparison)
+	}
+
+	return result, nil
+}
+
+// compareSecrets diffs two secrets key by key. Either secret may be nil when
+// it doesn't exist; the labels name each side in informational messages.
+// A key is redacted on both sides when either side's redaction settings hide it.
+func compareSecrets(path string, source, target *store.Secret, sourceLabel, targetLabel string, sourceRedaction, targetRedaction *config.Redaction) *ComparisonItem {
+	comparison := &ComparisonItem{
+		Path: path,
+	}
+
+	// Handle case where the secret exists only in target
+	if source == nil {
+		comparison.Diffs = append(comparison.Diffs, DiffItem{
+			Key:     "INFO",
+			Current: fmt.Sprintf("Secret doesn't exist in %s", sourceLabel),
+			Status:  "-",
+		})
+
+		for _, key := range sortedKeys(target.Data) {
+			comparison.Diffs = append(comparison.Diffs, DiffItem{
+				Key:        key,
+				Target:     secretValue(target, key, targetRedaction),
+				IsRedacted: shouldRedact(key, target, targetRedaction) && !target.Binary,
+				Status:     "-",
+			})
+		}
+
+		return comparison
+	}
+
+	// Handle case where the secret exists only in source
+	if target == nil {
+		comparison.Diffs = append(comparison.Diffs, DiffItem{
+			Key:    "INFO",
+			Target: fmt.Sprintf("Secret doesn't exist in %s", targetLabel),
+			Status: "+",
+		})
+
+		for _, key := range sortedKeys(source.Data) {
+			comparison.Diffs = append(comparison.Diffs, DiffItem{
+				Key:        key,
+				Current:    secretValue(source, key, sourceRedaction),
+				IsRedacted: shouldRedact(key, source, sourceRedaction) && !source.Binary,
+				Status:     "+",
+			})
+		}
+
+		return comparison
+	}
+
+	// A structured secret can't be compared with a single opaque string or binary content
+	if secretFormatName(source) != secretFormatName(target) {
+		comparison.Diffs = append(comparison.Diffs, DiffItem{
+			Key:     "INFO",
+			Current: fmt.Sprintf("Secret in %s is in %s format", sourceLabel, secretFormatName(source)),
+			Target:  fmt.Sprintf("Secret in %s is in %s format", targetLabel, secretFormatName(target)),
+			Status:  "*",
+		})
+
+		comparison.Diffs = append(comparison.Diffs, DiffItem{
+			Key:     "ERROR",
+			Current: fmt.Sprintf("Incompatible secret types: one is %s, the other is %s", secretFormatName(source), secretFormatName(target)),
+			Target:  "Cannot compare secrets with different formats",
+			Status:  "*",
+		})
+
+		return comparison
+	}
+
+	// Binary secrets are compared by content and only ever shown as hash and size
+	if source.Binary {
+		if source.BinarySummary() != target.BinarySummary() {
+			comparison.Diffs = append(comparison.Diffs, DiffItem{
+				Key:     "value",
+				Current: source.BinarySummary(),
+				Target:  target.BinarySummary(),
+				Status:  "*",
+			})
+		}
+
+		return comparison
+	}
+
+	// Both secrets exist, compare them
+	for _, key := range sortedKeys(source.Data) {
+		redacted := shouldRedact(key, source, sourceRedaction) || shouldRedact(key, target, targetRedaction)
+
+		targetValue, exists := target.Data[key]
+		if !exists {
+			comparison.Diffs = append(comparison.Diffs, DiffItem{
+				Key:        key,
+				Current:    secretValue(source, key, sourceRedaction),
+				IsRedacted: redacted,
+				Status:     "+",
+			})
+			continue
+		}
+
+		// Compare the raw values so differences hidden by JSON redaction still show up
+		sourceValueStr := store.StringValue(source.Data[key])
+		targetValueStr := store.StringValue(targetValue)
+		if sourceValueStr == targetValueStr {
+			continue
+		}
+
+		sourceValueStr = secretValue(source, key, sourceRedaction)
+		targetValueStr = secretValue(target, key, targetRedaction)
+
+		// Generate diff only if not redacted, and never between bytes
+		diffText := ""
+		if !redacted && !source.IsBinaryKey(key) && !target.IsBinaryKey(key) {
+			diffText = generateDiff(sourceValueStr, targetValueStr)
+		}
+
+		comparison.Diffs = append(comparison.Diffs, DiffItem{
+			Key:        key,
+			Current:    sourceValueStr,
+			Target:     targetValueStr,
+			Diff:       diffText,
+			IsRedacted: redacted,
+			Status:     "*", // Modified value
+		})
+	}
+
+	for _, key := range sortedKeys(target.Data) {
+		if _, exists := source.Data[key]; exists {
+			continue
+		}
+
+		comparison.Diffs = append(comparison.Diffs, DiffItem{
+			Key:        key,
+			Target:     secretValue(target, key, targetRedaction),
+			IsRedacted: shouldRedact(key, source, sourceRedaction) || shouldRedact(key, target, targetRedaction),
+			Status:     "-",
+		})
+	}
+
+	return comparison
+}
+
+// displayValue renders a value for output, redacting sensitive keys inside JSON values
+func displayValue(value interface{}, redaction *config.Redaction) string {
+	valueStr := store.StringValue(value)
+
+	redactedJSON, isJSON := tryParseAndRedactJSON(valueStr, redaction)
+	if isJSON {
+		return redactedJSON
+	}
+
+	return valueStr
+}
+
+// sortedKeys returns the keys of a secret in a stable order for output
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretValue returns the value of key for display. Binary secrets and
+// binary keys are described by hash and size instead of their content.
+func secretValue(secret *store.Secret, key string, redaction *config.Redaction) string {
+	if secret.Binary {
+		return secret.BinarySummary()
+	}
+	if secret.IsBinaryKey(key) {
+		return secret.KeySummary(key)
+	}
+	return displayValue(secret.Data[key], redaction)
+}
+
+// secretFormatName returns a human-readable name for the secret format
+func secretFormatName(secret *store.Secret) string {
+	switch {
+	case secret.Binary:
+		return "binary"
+	case secret.IsJSON:
+		return "JSON"
+	default:
+		return "string"
+	}
+}
+
+// generateDiff creates a text diff between two strings
+func generateDiff(current, target string) string {
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffMain(current, target, false)
+	return dmp.DiffPrettyText(diffs)
+}
@@ -0,0 +1,239 @@
+package comparison
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// CopyOptions represents options for copying secrets
+type CopyOptions struct {
+	Overwrite    bool
+	CopyConfig   bool
+	CopySecrets  bool
+	OnlyCopyKeys bool
+	Prune        bool // If true, keys not in source will be removed from target
+}
+
+// CopyResult represents the result of a copy operation
+type CopyResult struct {
+	SourcePath      string
+	TargetPath      string
+	SourceEnv       string
+	TargetEnv       string
+	SourceInstance  string
+	TargetInstance  string
+	SourceStoreType string
+	TargetStoreType string
+	Success         bool
+	Message         string
+	Keys            map[string]interface{} // Map of keys that were copied
+	Sensitive       map[string]bool        // Copied keys the target store treats as sensitive
+}
+
+// CopyBetweenStores copies a secret from one configured instance to another,
+// whatever the store type of either side
+func CopyBetweenStores(
+	sourceInstanceName, targetInstanceName, sourcePath, targetPath string,
+	sourceEnv, targetEnv, sourceKV, targetKV string,
+	configs *config.Configs,
+	options CopyOptions,
+) (*CopyResult, error) {
+	// Check if source and target are the same
+	if sourceInstanceName == targetInstanceName && sourcePath == targetPath && sourceKV == targetKV {
+		return nil, fmt.Errorf("source and target are the same, cannot copy to self")
+	}
+
+	sourceStore, err := NewStore(sourceInstanceName, configs, StoreOptions{Env: sourceEnv, KVEngine: sourceKV})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source store: %w", err)
+	}
+	defer CloseStore(sourceStore)
+
+	targetStore, err := NewStore(targetInstanceName, configs, StoreOptions{Env: targetEnv, KVEngine: targetKV})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open target store: %w", err)
+	}
+	defer CloseStore(targetStore)
+
+	// Initialize result
+	result := &CopyResult{
+		SourcePath:      sourcePath,
+		TargetPath:      targetPath,
+		SourceEnv:       sourceEnv,
+		TargetEnv:       targetEnv,
+		SourceInstance:  sourceInstanceName,
+		TargetInstance:  targetInstanceName,
+		SourceStoreType: sourceStore.Type(),
+		TargetStoreType: targetStore.Type(),
+		Success:         false,
+	}
+
+	if err := checkAccess(sourceStore, sourcePath, false); err != nil {
+		return nil, fmt.Errorf("cannot read source secret: %w", err)
+	}
+
+	sourceSecret, err := sourceStore.GetSecret(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source secret: %w", err)
+	}
+
+	redaction := configs.RedactionFor(sourceInstanceName, sourcePath)
+
+	if sourceSecret.Binary {
+		// Binary content can't be partially copied, and only some stores can give it back as bytes
+		binaryStore, ok := targetStore.(store.BinaryStore)
+		if !ok || !binaryStore.SupportsBinary() {
+			return nil, fmt.Errorf("cannot copy binary %s secret to %s", sourceStore.Type(), targetStore.Type())
+		}
+		if shouldRedact("value", sourceSecret, redaction) && !options.CopySecrets {
+			return nil, fmt.Errorf("binary secret %s is sensitive, use --copy-secrets to copy its content", sourcePath)
+		}
+	} else if !sourceSecret.IsJSON && sourceStore.Type() != targetStore.Type() {
+		// A single opaque string only keeps its meaning in a store of the same type
+		return nil, fmt.Errorf("cannot copy non-JSON %s secret to %s", sourceStore.Type(), targetStore.Type())
+	}
+
+	// Create the target location if the store needs one
+	if provisioner, ok := targetStore.(store.Provisioner); ok {
+		if err := provisioner.Provision(); err != nil {
+			return nil, fmt.Errorf("failed to prepare target store: %w", err)
+		}
+	}
+
+	if err := checkAccess(targetStore, targetPath, true); err != nil {
+		return nil, fmt.Errorf("cannot write target secret: %w", err)
+	}
+
+	targetSecret, err := getSecretIfExists(targetStore, targetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target secret: %w", err)
+	}
+
+	resultSecret, copiedKeys := mergeSecret(sourceSecret, targetSecret, redaction, configs, options)
+
+	// Other stores would keep the base64 text of a binary key, not its bytes
+	if sourceStore.Type() != targetStore.Type() {
+		for _, key := range sortedKeys(copiedKeys) {
+			if resultSecret.IsBinaryKey(key) {
+				return nil, fmt.Errorf("cannot copy binary key %s of %s secret to %s", key, sourceStore.Type(), targetStore.Type())
+			}
+		}
+	}
+
+	err = targetStore.WriteSecret(targetPath, resultSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write target secret: %w", err)
+	}
+
+	result.Success = true
+	result.Keys = copiedKeys
+	result.Sensitive = make(map[string]bool, len(copiedKeys))
+	for key := range copiedKeys {
+		result.Sensitive[key] = resultSecret.IsSensitive(key)
+	}
+	result.Message = fmt.Sprintf("Successfully copied secret from %s to %s", sourcePath, targetPath)
+	return result, nil
+}
+
+// mergeSecret builds the secret to write to the target according to the copy options
+// and the redaction settings of the source. It returns the merged secret and the keys
+// that were taken from the source.
+func mergeSecret(source, target *store.Secret, redaction *config.Redaction, configs *config.Configs, options CopyOptions) (*store.Secret, map[string]interface{}) {
+	copiedKeys := make(map[string]interface{})
+
+	// Binary secrets are copied whole; only their hash and size are reported
+	if source.Binary {
+		result := store.NewSecret(map[string]interface{}{"value": source.Data["value"]})
+		result.IsJSON = false
+		result.Binary = true
+		result.Sensitive["value"] = source.IsSensitive("value")
+		copiedKeys["value"] = source.BinarySummary()
+		return result, copiedKeys
+	}
+
+	// Special handling for non-JSON secrets
+	if !source.IsJSON {
+		valueStr := store.StringValue(source.Data["value"])
+
+		// Redact if security settings require it
+		if shouldRedact("value", source, redaction) && !options.CopySecrets {
+			valueStr = ""
+		}
+
+		result := store.NewSecret(map[string]interface{}{"value": valueStr})
+		result.IsJSON = false
+		result.Sensitive["value"] = source.IsSensitive("value")
+		copiedKeys["value"] = valueStr
+		return result, copiedKeys
+	}
+
+	result := store.NewSecret(nil)
+
+	// Start with existing target data unless pruning is enabled
+	if target != nil && target.IsJSON && !options.Prune {
+		for k, v := range target.Data {
+			result.Data[k] = v
+			result.Sensitive[k] = target.IsSensitive(k)
+			result.BinaryKeys[k] = target.IsBinaryKey(k)
+		}
+	}
+
+	// Process each source key according to options
+	for _, key := range sortedKeys(source.Data) {
+		// Skip existing keys if not overwriting
+		if _, exists := result.Data[key]; exists && !options.Overwrite {
+			continue
+		}
+
+		isRedactedKey := shouldRedact(key, source, redaction)
+
+		// Filter keys based on copy options
+		if isRedactedKey && !options.CopySecrets && !options.CopyConfig {
+			continue
+		}
+
+		if !isRedactedKey && options.CopySecrets && !options.CopyConfig {
+			continue
+		}
+
+		// Convert to string for processing
+		valueStr := store.StringValue(source.Data[key])
+
+		// Handle JSON values if needed
+		if redaction.RedactJSONValues && isJSONValue(valueStr) {
+			var jsonData interface{}
+			if err := json.Unmarshal([]byte(valueStr), &jsonData); err == nil {
+				if options.OnlyCopyKeys {
+					// Only copy the keys, not the values
+					jsonData = extractJSONStructure(jsonData)
+				} else if isRedactedKey && !options.CopySecrets {
+					// Redact the values
+					jsonData = redactJSONValues(jsonData, redaction)
+				}
+
+				// Convert back to string
+				jsonBytes, err := json.Marshal(jsonData)
+				if err == nil {
+					valueStr = string(jsonBytes)
+				}
+			}
+		} else if options.OnlyCopyKeys || (isRedactedKey && !options.CopySecrets) {
+			// For non-JSON values, redact if needed
+			valueStr = ""
+		}
+
+		// Add to result data, telling the target which values to protect
+		result.Data[key] = valueStr
+		result.Sensitive[key] = source.IsSensitive(key) || isSensitiveKeyName(key, configs)
+		result.BinaryKeys[key] = source.IsBinaryKey(key) && valueStr != ""
+		copiedKeys[key] = valueStr
+		if result.BinaryKeys[key] {
+			copiedKeys[key] = source.KeySummary(key)
+		}
+	}
+
+	return result, copiedKeys
+}
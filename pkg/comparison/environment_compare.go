@@ -0,0 +1,44 @@
+package comparison
+
+import (
+	"github.com/secretz/vault-promoter/pkg/config"
+)
+
+// CompareEnvironments compares an app's secret between two environments of the
+// promotion chain, at the paths given by the path template of each side's store.
+// Both environments are read from instanceName when it is set; otherwise each
+// one is read from the store configured under its own name.
+func CompareEnvironments(instanceName, appName, currentEnv, targetEnv, pathSuffix, kvEngine string, configs *config.Configs) (*ComparisonResult, error) {
+	currentInstance, targetInstance := instanceName, instanceName
+	if instanceName == "" {
+		currentInstance, targetInstance = currentEnv, targetEnv
+	}
+
+	currentPath, err := configs.SecretPath(currentInstance, appName, currentEnv, pathSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPath, err := configs.SecretPath(targetInstance, appName, targetEnv, pathSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything under a secret suffix is sensitive, whatever the key name
+	if config.IsSecretSuffix(pathSuffix) {
+		var secretPaths []string
+		if configs.UsesSuffix(currentInstance) {
+			secretPaths = append(secretPaths, currentPath)
+		}
+		if configs.UsesSuffix(targetInstance) {
+			secretPaths = append(secretPaths, targetPath)
+		}
+		configs = configs.WithSecretPaths(secretPaths...)
+	}
+
+	return CompareStores(
+		currentInstance, targetInstance, currentPath, targetPath,
+		currentEnv, targetEnv, kvEngine, kvEngine,
+		configs,
+	)
+}
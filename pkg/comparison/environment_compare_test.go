@@ -0,0 +1,99 @@
+package comparison
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+)
+
+// localConfigs returns a config whose dev and prod environments are JSON
+// files in dir, laid out as {app}/{env}/{suffix}
+func localConfigs(dir string) *config.Configs {
+	local := config.EnvironmentConfig{Store: config.StoreLocalFile, Directory: dir, Format: "json"}
+	return &config.Configs{
+		Environments: map[string]config.EnvironmentConfig{"dev": local, "prod": local},
+	}
+}
+
+func writeJSONFile(t *testing.T, file string, data map[string]interface{}) {
+	t.Helper()
+	content, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompareEnvironmentsRedactsSecretSuffix(t *testing.T) {
+	dir := t.TempDir()
+	configs := localConfigs(dir)
+
+	for _, suffix := range []string{"config", "secrets"} {
+		writeJSONFile(t, filepath.Join(dir, "billing", "dev", suffix), map[string]interface{}{"db_host": "db-dev", "db_password": "dev-pw"})
+		writeJSONFile(t, filepath.Join(dir, "billing", "prod", suffix), map[string]interface{}{"db_host": "db-prod", "db_password": "prod-pw"})
+	}
+
+	tests := []struct {
+		suffix         string
+		hostIsRedacted bool
+	}{
+		{suffix: "config", hostIsRedacted: false},
+		{suffix: "secrets", hostIsRedacted: true},
+	}
+
+	for _, tt := range tests {
+		result, err := CompareEnvironments("", "billing", "dev", "prod", tt.suffix, "", configs)
+		if err != nil {
+			t.Fatalf("CompareEnvironments(%s) error = %v", tt.suffix, err)
+		}
+		if len(result.Comparisons) != 1 {
+			t.Fatalf("CompareEnvironments(%s) = %d comparisons, want 1", tt.suffix, len(result.Comparisons))
+		}
+
+		for _, diff := range result.Comparisons[0].Diffs {
+			want := diff.Key == "db_password" || tt.hostIsRedacted
+			if diff.IsRedacted != want {
+				t.Errorf("CompareEnvironments(%s): %s redacted = %v, want %v", tt.suffix, diff.Key, diff.IsRedacted, want)
+			}
+		}
+	}
+}
+
+func TestCopyKeepsKeysUnderSecretPaths(t *testing.T) {
+	dir := t.TempDir()
+	configs := localConfigs(dir)
+
+	writeJSONFile(t, filepath.Join(dir, "app", "dev", "secrets"), map[string]interface{}{"db_host": "db-dev", "db_password": "dev-pw"})
+
+	// env-compare redacts the whole path, but copy only goes by key names
+	result, err := CopyBetweenStores("dev", "prod", "app/dev/secrets", "app/prod/secrets", "dev", "prod", "", "", configs, CopyOptions{})
+	if err != nil {
+		t.Fatalf("CopyBetweenStores() error = %v", err)
+	}
+	if _, ok := result.Keys["db_host"]; !ok {
+		t.Errorf("CopyBetweenStores() copied %v, want db_host", result.Keys)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "app", "prod", "secrets"))
+	if err != nil {
+		t.Fatalf("target file not written: %v", err)
+	}
+	var written map[string]interface{}
+	if err := json.Unmarshal(content, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written["db_host"] != "db-dev" {
+		t.Errorf("target = %v, want db_host copied", written)
+	}
+	if _, ok := written["db_password"]; ok {
+		t.Errorf("target = %v, want db_password left out without --copy-secrets", written)
+	}
+}
@@ -0,0 +1,156 @@
+package comparison
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// HistoryResult holds the key-level changelog of a secret
+type HistoryResult struct {
+	Path      string
+	Instance  string
+	StoreType string
+	Entries   []*HistoryEntry
+}
+
+// HistoryEntry is one version of a secret and the key changes it introduced
+type HistoryEntry struct {
+	Version     string
+	CreatedTime time.Time
+	Author      string
+	Deleted     bool       // the version can't be read anymore, so its changes are unknown
+	Changes     []DiffItem // relative to the previous readable version
+}
+
+// SecretHistory reads every version of a secret and diffs each one against
+// the previous readable version, oldest first
+func SecretHistory(instanceName, path, env, kvEngine string, configs *config.Configs) (*HistoryResult, error) {
+	secretStore, versions, err := listVersions(instanceName, path, env, kvEngine, configs)
+	if err != nil {
+		return nil, err
+	}
+	defer CloseStore(secretStore)
+
+	result := &HistoryResult{
+		Path:      path,
+		Instance:  instanceName,
+		StoreType: secretStore.Type(),
+	}
+
+	var previous *store.Secret
+	for _, version := range versions {
+		entry := &HistoryEntry{
+			Version:     version.Version,
+			CreatedTime: version.CreatedTime,
+			Author:      version.Author,
+			Deleted:     version.Deleted,
+		}
+		result.Entries = append(result.Entries, entry)
+
+		if version.Deleted {
+			continue
+		}
+
+		secret, err := readVersion(secretStore, path, version.Version)
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			entry.Deleted = true
+			continue
+		}
+
+		entry.Changes = versionChanges(previous, secret, configs.RedactionFor(instanceName, path))
+		previous = secret
+	}
+
+	return result, nil
+}
+
+// listVersions opens the store of the instance and lists the versions of the secret at path.
+// The caller closes the store.
+func listVersions(instanceName, path, env, kvEngine string, configs *config.Configs) (store.SecretStore, []store.VersionInfo, error) {
+	secretStore, err := NewStore(instanceName, configs, StoreOptions{Env: env, KVEngine: kvEngine})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	versioned, ok := secretStore.(store.VersionedStore)
+	if !ok {
+		CloseStore(secretStore)
+		return nil, nil, fmt.Errorf("%s store keeps no version history", secretStore.Type())
+	}
+
+	versions, err := versioned.ListVersions(path)
+	if err != nil {
+		CloseStore(secretStore)
+		return nil, nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	return secretStore, versions, nil
+}
+
+// readVersion reads one version of a secret, returning nil when it can't be read anymore
+func readVersion(secretStore store.SecretStore, path, version string) (*store.Secret, error) {
+	secret, err := getSecretIfExists(secretStore, path+store.VersionSeparator+version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version %s: %w", version, err)
+	}
+	return secret, nil
+}
+
+// versionChanges lists the keys added, changed and removed between two versions.
+// Current holds the value in the newer version and Target the one it replaced.
+func versionChanges(previous, current *store.Secret, redaction *config.Redaction) []DiffItem {
+	if previous == nil {
+		previous = store.NewSecret(nil)
+	}
+
+	var changes []DiffItem
+	for _, key := range sortedKeys(current.Data) {
+		// Binary versions only ever show their hash and size
+		redacted := (shouldRedact(key, current, redaction) && !current.Binary) ||
+			(shouldRedact(key, previous, redaction) && !previous.Binary)
+
+		oldValue, existed := previous.Data[key]
+		if !existed {
+			changes = append(changes, DiffItem{
+				Key:        key,
+				Current:    secretValue(current, key, redaction),
+				IsRedacted: redacted,
+				Status:     "+",
+			})
+			continue
+		}
+
+		if store.StringValue(oldValue) == store.StringValue(current.Data[key]) {
+			continue
+		}
+
+		changes = append(changes, DiffItem{
+			Key:        key,
+			Current:    secretValue(current, key, redaction),
+			Target:     secretValue(previous, key, redaction),
+			IsRedacted: redacted,
+			Status:     "*",
+		})
+	}
+
+	for _, key := range sortedKeys(previous.Data) {
+		if _, exists := current.Data[key]; exists {
+			continue
+		}
+
+		changes = append(changes, DiffItem{
+			Key:        key,
+			Target:     secretValue(previous, key, redaction),
+			IsRedacted: shouldRedact(key, previous, redaction) && !previous.Binary,
+			Status:     "-",
+		})
+	}
+
+	return changes
+}
@@ -0,0 +1,44 @@
+package comparison
+
+import (
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+func TestVersionChangesBinary(t *testing.T) {
+	redaction := &config.Redaction{RedactSecrets: true, RedactAll: true}
+
+	previous := store.NewBinarySecret([]byte{0x01, 0xff})
+	previous.MarkAllSensitive()
+	current := store.NewBinarySecret([]byte{0x02, 0xfe, 0x00})
+	current.MarkAllSensitive()
+
+	changes := versionChanges(previous, current, redaction)
+	if len(changes) != 1 {
+		t.Fatalf("versionChanges() = %v, want one change", changes)
+	}
+	change := changes[0]
+	if change.Status != "*" || change.IsRedacted {
+		t.Errorf("versionChanges() = status %s, redacted %v, want a visible change", change.Status, change.IsRedacted)
+	}
+	if change.Current != current.BinarySummary() || change.Target != previous.BinarySummary() {
+		t.Errorf("versionChanges() = %q, %q, want the hash and size of both versions", change.Current, change.Target)
+	}
+
+	// Removed keys of a binary version aren't redacted either
+	text := store.NewSecret(map[string]interface{}{"other": "x"})
+	for _, change := range versionChanges(previous, text, &config.Redaction{RedactSecrets: true}) {
+		if change.Key == "value" && (change.Status != "-" || change.IsRedacted) {
+			t.Errorf("versionChanges() = status %s, redacted %v for the binary value, want a visible removal", change.Status, change.IsRedacted)
+		}
+	}
+
+	// Text values still follow the redaction settings
+	older := store.NewSecret(map[string]interface{}{"password": "old"})
+	newer := store.NewSecret(map[string]interface{}{"password": "new"})
+	if changes := versionChanges(older, newer, redaction); len(changes) != 1 || !changes[0].IsRedacted {
+		t.Errorf("versionChanges() = %v, want the password change redacted", changes)
+	}
+}
@@ -0,0 +1,130 @@
+package comparison
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// shouldRedact determines if the value of a key should be hidden under the
+// redaction settings resolved for its secret, taking into account whether
+// the backend flagged it as sensitive
+func shouldRedact(key string, secret *store.Secret, redaction *config.Redaction) bool {
+	return redaction.Decide(key, secret.IsSensitive(key)).Redacted
+}
+
+// isRedactedKeyName checks the key name alone against the redaction rules
+func isRedactedKeyName(key string, redaction *config.Redaction) bool {
+	return redaction.Decide(key, false).Redacted
+}
+
+// isSensitiveKeyName checks the key name against the sensitive keys rules,
+// whether or not redaction is enabled for output
+func isSensitiveKeyName(key string, configs *config.Configs) bool {
+	rules, err := config.ParseKeyRules(configs.GetSensitiveKeys())
+	if err != nil {
+		return false
+	}
+	return rules.Contains(key)
+}
+
+// tryParseAndRedactJSON attempts to parse and redact a JSON string
+func tryParseAndRedactJSON(value string, redaction *config.Redaction) (string, bool) {
+	if !redaction.RedactJSONValues || !isJSONValue(value) {
+		return value, false
+	}
+
+	var data interface{}
+	err := json.Unmarshal([]byte(value), &data)
+	if err != nil {
+		return value, false
+	}
+
+	// Redact JSON values
+	redactedData := redactJSONValues(data, redaction)
+
+	redactedJSON, err := json.MarshalIndent(redactedData, "", "  ")
+	if err != nil {
+		return value, false
+	}
+
+	return string(redactedJSON), true
+}
+
+// isJSONValue checks if a string is a valid JSON object or array
+func isJSONValue(s string) bool {
+	s = strings.TrimSpace(s)
+	isObject := strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")
+	isArray := strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]")
+	if !isObject && !isArray {
+		return false
+	}
+
+	var js interface{}
+	return json.Unmarshal([]byte(s), &js) == nil
+}
+
+// redactJSONValues recursively redacts sensitive values in JSON data
+func redactJSONValues(data interface{}, redaction *config.Redaction) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		// Process each key in the map
+		result := make(map[string]interface{})
+		for key, value := range v {
+			// Nested keys carry no backend flags, so only the name is checked
+			if isRedactedKeyName(key, redaction) {
+				result[key] = "(redacted)"
+			} else {
+				// Recursively process nested values
+				result[key] = redactJSONValues(value, redaction)
+			}
+		}
+		return result
+
+	case []interface{}:
+		// Process each item in the array
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactJSONValues(item, redaction)
+		}
+		return result
+
+	default:
+		// Return primitive values as is
+		return v
+	}
+}
+
+// extractJSONStructure creates a copy of the JSON structure with empty values
+func extractJSONStructure(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		// Process each key in the map
+		result := make(map[string]interface{})
+		for key, value := range v {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				// Recursively process nested structures
+				result[key] = extractJSONStructure(value)
+			default:
+				// Replace primitive values with empty string
+				result[key] = ""
+			}
+		}
+		return result
+
+	case []interface{}:
+		// Process each item in the array
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = extractJSONStructure(item)
+		}
+		return result
+
+	default:
+		// Return empty string for primitive values
+		return ""
+	}
+}
@@ -0,0 +1,134 @@
+package comparison
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/secretz/vault-promoter/pkg/awssecretsmanager"
+	"github.com/secretz/vault-promoter/pkg/awsssm"
+	"github.com/secretz/vault-promoter/pkg/azurekeyvault"
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/consul"
+	"github.com/secretz/vault-promoter/pkg/filestore"
+	"github.com/secretz/vault-promoter/pkg/gcpsecretmanager"
+	"github.com/secretz/vault-promoter/pkg/kubernetes"
+	"github.com/secretz/vault-promoter/pkg/localfile"
+	"github.com/secretz/vault-promoter/pkg/plugin"
+	"github.com/secretz/vault-promoter/pkg/store"
+	"github.com/secretz/vault-promoter/pkg/vault"
+)
+
+// StoreOptions holds the per-command settings needed to open a store
+type StoreOptions struct {
+	Env      string // environment name the store is used for
+	KVEngine string // KV engine to use when the store is Vault
+}
+
+// NewStore opens the secret store configured for the given instance.
+// This is the only place that needs to know about every backend.
+func NewStore(instanceName string, configs *config.Configs, options StoreOptions) (store.SecretStore, error) {
+	envConfig, err := configs.GetEnvironmentConfig(instanceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config for %s: %w", instanceName, err)
+	}
+
+	switch envConfig.Store {
+	case config.StoreVault, "":
+		client, err := vault.NewClient(envConfig, vault.Environment(options.Env), options.KVEngine)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Vault client: %w", err)
+		}
+		return client, nil
+	case config.StoreAWSSecretsManager:
+		client, err := awssecretsmanager.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS client: %w", err)
+		}
+		return client, nil
+	case config.StoreAWSSSM:
+		client, err := awsssm.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS SSM client: %w", err)
+		}
+		return client, nil
+	case config.StoreAzureKeyVault:
+		client, err := azurekeyvault.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure Key Vault client: %w", err)
+		}
+		return client, nil
+	case config.StoreGCPSecretManager:
+		client, err := gcpsecretmanager.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCP Secret Manager client: %w", err)
+		}
+		return client, nil
+	case config.StoreKubernetes:
+		client, err := kubernetes.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+		}
+		return client, nil
+	case config.StoreFile:
+		client, err := filestore.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file store: %w", err)
+		}
+		return client, nil
+	case config.StoreLocalFile:
+		client, err := localfile.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create local file client: %w", err)
+		}
+		return client, nil
+	case config.StoreConsul:
+		client, err := consul.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Consul client: %w", err)
+		}
+		return client, nil
+	case config.StorePlugin:
+		client, err := plugin.NewClient(envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create plugin client: %w", err)
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unsupported store type: %s", envConfig.Store)
+	}
+}
+
+// CloseStore releases what a store holds open, such as a plugin process or a
+// Vault token renewal. Stores without anything to release are left alone.
+func CloseStore(secretStore store.SecretStore) {
+	closer, ok := secretStore.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to close %s store: %v\n", secretStore.Type(), err)
+	}
+}
+
+// getSecretIfExists reads a secret, returning nil without error when it doesn't exist
+func getSecretIfExists(secretStore store.SecretStore, path string) (*store.Secret, error) {
+	secret, err := secretStore.GetSecret(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// checkAccess asks stores that support it whether path can be read, or written
+// when write is set, so that missing permissions fail before anything changes
+func checkAccess(secretStore store.SecretStore, path string, write bool) error {
+	checker, ok := secretStore.(store.AccessChecker)
+	if !ok {
+		return nil
+	}
+	return checker.CheckAccess(path, write)
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Supported Vault auth methods
+const (
+	AuthToken      = "token"
+	AuthAppRole    = "approle"
+	AuthKubernetes = "kubernetes"
+	AuthJWT        = "jwt"
+	AuthUserpass   = "userpass"
+	AuthAWS        = "aws"
+)
+
+// DefaultServiceAccountTokenFile is where Kubernetes mounts the pod's service account token
+const DefaultServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultAuthConfig selects how the CLI logs in to a Vault environment.
+// Secrets are never written in the config, only the variables or files holding them.
+type VaultAuthConfig struct {
+	Method string `json:"method"`
+	Mount  string `json:"mount,omitempty"` // auth mount path, defaults to the method name
+	Role   string `json:"role,omitempty"`  // Vault role for kubernetes, jwt and aws
+
+	// AppRole settings
+	RoleID       string `json:"role_id,omitempty"`
+	RoleIDEnv    string `json:"role_id_env,omitempty"`
+	SecretIDEnv  string `json:"secret_id_env,omitempty"`
+	SecretIDFile string `json:"secret_id_file,omitempty"`
+
+	// Kubernetes and JWT/OIDC settings
+	JWTEnv  string `json:"jwt_env,omitempty"`
+	JWTFile string `json:"jwt_file,omitempty"` // kubernetes defaults to the service account token
+
+	// Userpass settings
+	Username    string `json:"username,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"`
+
+	// AWS IAM settings
+	Region   string `json:"region,omitempty"`    // region of the STS endpoint, defaults to us-east-1
+	ServerID string `json:"server_id,omitempty"` // value of the X-Vault-AWS-IAM-Server-ID header, if the role requires it
+}
+
+// GetMount returns the path the auth method is mounted at
+func (a *VaultAuthConfig) GetMount() string {
+	if mount := strings.Trim(a.Mount, "/"); mount != "" {
+		return mount
+	}
+	return a.Method
+}
+
+// Validate checks that the fields required by the auth method are set
+func (a *VaultAuthConfig) Validate() error {
+	switch a.Method {
+	case AuthToken:
+		// The token itself comes from token_env
+	case AuthAppRole:
+		if a.RoleID == "" && a.RoleIDEnv == "" {
+			return fmt.Errorf("'role_id' or 'role_id_env' is required for approle auth")
+		}
+		if a.SecretIDEnv != "" && a.SecretIDFile != "" {
+			return fmt.Errorf("only one of 'secret_id_env' and 'secret_id_file' may be set for approle auth")
+		}
+	case AuthKubernetes, AuthJWT:
+		if a.Role == "" {
+			return fmt.Errorf("'role' is required for %s auth", a.Method)
+		}
+		if a.JWTEnv != "" && a.JWTFile != "" {
+			return fmt.Errorf("only one of 'jwt_env' and 'jwt_file' may be set for %s auth", a.Method)
+		}
+		if a.Method == AuthJWT && a.JWTEnv == "" && a.JWTFile == "" {
+			return fmt.Errorf("'jwt_env' or 'jwt_file' is required for jwt auth")
+		}
+	case AuthUserpass:
+		if a.Username == "" || a.PasswordEnv == "" {
+			return fmt.Errorf("'username' and 'password_env' are required for userpass auth")
+		}
+	case AuthAWS:
+		if a.Role == "" {
+			return fmt.Errorf("'role' is required for aws auth")
+		}
+	case "":
+		return fmt.Errorf("auth 'method' is required")
+	default:
+		return fmt.Errorf("unsupported auth method: %s", a.Method)
+	}
+
+	return nil
+}
+
+// GetRoleID returns the AppRole role ID, from the config or its environment variable
+func (a *VaultAuthConfig) GetRoleID() (string, error) {
+	if a.RoleID != "" {
+		return a.RoleID, nil
+	}
+	return readEnv(a.RoleIDEnv)
+}
+
+// GetSecretID returns the AppRole secret ID. It is empty when the role doesn't bind one.
+func (a *VaultAuthConfig) GetSecretID() (string, error) {
+	switch {
+	case a.SecretIDFile != "":
+		return readFile(a.SecretIDFile)
+	case a.SecretIDEnv != "":
+		return readEnv(a.SecretIDEnv)
+	}
+	return "", nil
+}
+
+// GetJWT returns the JWT presented to the kubernetes or jwt auth method
+func (a *VaultAuthConfig) GetJWT() (string, error) {
+	switch {
+	case a.JWTEnv != "":
+		return readEnv(a.JWTEnv)
+	case a.JWTFile != "":
+		return readFile(a.JWTFile)
+	}
+	return readFile(DefaultServiceAccountTokenFile)
+}
+
+// GetPassword returns the userpass password from its environment variable
+func (a *VaultAuthConfig) GetPassword() (string, error) {
+	return readEnv(a.PasswordEnv)
+}
+
+// readEnv reads a required credential from an environment variable
+func readEnv(name string) (string, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s not set or empty", name)
+	}
+	return value, nil
+}
+
+// readFile reads a required credential from a file
+func readFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return value, nil
+}
@@ -1,18 +1,94 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
+	"time"
+)
+
+// Supported store types
+const (
+	StoreVault             = "vault"
+	StoreAWSSecretsManager = "awssecretsmanager"
+	StoreAzureKeyVault     = "azurekeyvault"
+	StoreGCPSecretManager  = "gcpsecretmanager"
+	StoreKubernetes        = "kubernetes"
+	StoreAWSSSM            = "awsssm"
+	StoreFile              = "file"
+	StoreLocalFile         = "localfile"
+	StorePlugin            = "plugin"
+	StoreConsul            = "consul"
 )
 
 // EnvironmentConfig represents a Vault environment configuration
 type EnvironmentConfig struct {
-	URL      string `json:"url"`
-	TokenEnv string `json:"token_env"`
-	Store    string `json:"store"`
-	Role     string `json:"role,omitempty"`
+	URL      string `json:"url,omitempty"`
+	TokenEnv string `json:"token_env,omitempty"`
+	Store    string `json:"store,omitempty"`
+	Role     string `json:"role,omitempty"` // IAM role assumed by the AWS stores
+
+	// Vault settings
+	Namespace    string           `json:"namespace,omitempty"`     // Vault Enterprise namespace, e.g. "admin/team-a"
+	Auth         *VaultAuthConfig `json:"auth,omitempty"`          // login method, defaults to a static token
+	TokenFile    string           `json:"token_file,omitempty"`    // file holding the token, e.g. a Vault Agent sink or ~/.vault-token
+	TokenHelper  string           `json:"token_helper,omitempty"`  // Vault token helper executable, called with "get"
+	TokenCommand string           `json:"token_command,omitempty"` // shell command printing the token
+	KVVersion    string           `json:"kv_version,omitempty"`    // "1" or "2", used when the token can't look up the mount
+
+	// AWS settings
+	Region               string `json:"region,omitempty"`                  // defaults to AWS_REGION or the profile's region
+	Profile              string `json:"profile,omitempty"`                 // shared config profile, defaults to AWS_PROFILE
+	ExternalID           string `json:"external_id,omitempty"`             // external ID required by the role's trust policy
+	RoleSessionName      string `json:"role_session_name,omitempty"`       // defaults to "vault-promoter"
+	SessionDuration      string `json:"session_duration,omitempty"`        // e.g. "1h", defaults to 15 minutes
+	MFASerial            string `json:"mfa_serial,omitempty"`              // MFA device of the role; the code is read from stdin
+	WebIdentityTokenFile string `json:"web_identity_token_file,omitempty"` // assume the role with a web identity token, e.g. EKS IRSA
+
+	// Azure Key Vault settings
+	VaultURI        string `json:"vault_uri,omitempty"`
+	TenantID        string `json:"tenant_id,omitempty"`
+	ClientID        string `json:"client_id,omitempty"`
+	ClientSecretEnv string `json:"client_secret_env,omitempty"`
+	AuthorityHost   string `json:"authority_host,omitempty"` // defaults to the public Azure cloud
+
+	// GCP Secret Manager settings
+	ProjectID       string `json:"project_id,omitempty"`
+	CredentialsFile string `json:"credentials_file,omitempty"` // service account key, defaults to GOOGLE_APPLICATION_CREDENTIALS
+
+	// Kubernetes settings
+	Kubeconfig    string `json:"kubeconfig,omitempty"`     // defaults to $KUBECONFIG, ~/.kube/config, then in-cluster
+	KubeContext   string `json:"kube_context,omitempty"`   // defaults to the current context
+	KubeNamespace string `json:"kube_namespace,omitempty"` // used for paths without a namespace
+
+	// Encrypted and plain local file store settings
+	Directory string `json:"directory,omitempty"`
+	KeyEnv    string `json:"key_env,omitempty"` // environment variable holding the base64 AES-256 key
+	Format    string `json:"format,omitempty"`  // file: json (default) or yaml; localfile: dotenv, json or yaml (default from the file name)
+
+	// Consul settings
+	Datacenter string `json:"datacenter,omitempty"` // defaults to the agent's datacenter
+
+	// External store plugin settings
+	Plugin       string            `json:"plugin,omitempty"`        // executable speaking the plugin protocol
+	PluginArgs   []string          `json:"plugin_args,omitempty"`   // arguments passed to the executable
+	PluginConfig map[string]string `json:"plugin_config,omitempty"` // settings sent to the plugin on init
+
+	// Endpoint overrides the API endpoint of the store, e.g. to target a local fake
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// PathTemplate overrides promotion.path_template for the secrets of this store
+	PathTemplate string `json:"path_template,omitempty"`
+
+	// TLS and HTTP transport settings for Vault and AWS
+	CACert        string `json:"ca_cert,omitempty"`         // PEM file with the CA certificates to trust
+	CAPath        string `json:"ca_path,omitempty"`         // directory of PEM CA certificates to trust
+	ClientCert    string `json:"client_cert,omitempty"`     // PEM client certificate for mutual TLS
+	ClientKey     string `json:"client_key,omitempty"`      // PEM private key of client_cert
+	TLSServerName string `json:"tls_server_name,omitempty"` // name to verify the server certificate against
+	Proxy         string `json:"proxy,omitempty"`           // HTTP(S) proxy URL, defaults to HTTPS_PROXY
+	Timeout       string `json:"timeout,omitempty"`         // request timeout, e.g. "30s"
 }
 
 // Configs represents the entire configuration file
@@ -22,19 +98,35 @@ type Configs struct {
 	RedactSecrets    *bool                        `json:"redact_secrets,omitempty"`
 	RedactJSONValues *bool                        `json:"redact_json_values,omitempty"`
 	SensitiveKeys    []string                     `json:"sensitive_keys,omitempty"`
+
+	// RedactionPolicies override the redaction settings above for some environments and paths
+	RedactionPolicies []RedactionPolicy `json:"redaction_policies,omitempty"`
+
+	// Promotion describes the environment chain and path layout used by env-compare
+	Promotion *PromotionConfig `json:"promotion,omitempty"`
+
+	// Warnings lists problems found while reading the file that don't stop it
+	// from being used, such as unknown fields
+	Warnings []string `json:"-"`
+
+	// Sources lists the files the configuration was merged from, lowest
+	// precedence first
+	Sources []string `json:"-"`
+
+	// secretPaths are redacted whole, see WithSecretPaths
+	secretPaths []string
 }
 
-// DefaultRedactedKeys is a list of key names that typically contain sensitive information
+// DefaultRedactedKeys is a list of key rules that typically match sensitive information.
+// Short words like "key" only match as a whole name or a suffix, so monkey_count stays visible.
 var DefaultRedactedKeys = []string{
-	"password", "secret", "token", "key", "credential", "auth", "pwd", "pass",
-	"apikey", "api_key", "access_key", "secret_key", "private_key", "cert", "certificate",
+	"*password*", "*passwd*", "*secret*", "*token*", "*credential*", "*apikey*", "*api_key*",
+	"*access_key*", "*private_key*", "*pwd*", "exact:key", "*_key", "*-key", "exact:auth", "*_auth", "auth_*",
+	"exact:pass", "*_pass", "exact:cert", "*_cert", "*certificate*",
 }
 
-// DefaultSensitiveKeys is a list of key names that typically contain sensitive information and should be split
-var DefaultSensitiveKeys = []string{
-	"password", "secret", "token", "key", "credential", "auth", "pwd", "pass",
-	"apikey", "api_key", "access_key", "secret_key", "private_key", "cert", "certificate",
-}
+// DefaultSensitiveKeys is a list of key rules that typically match sensitive information and should be split
+var DefaultSensitiveKeys = DefaultRedactedKeys
 
 // ShouldRedactSecrets returns whether secrets should be redacted
 func (c *Configs) ShouldRedactSecrets() bool {
@@ -68,27 +160,35 @@ func (c *Configs) GetSensitiveKeys() []string {
 	return c.SensitiveKeys
 }
 
-// ReadConfigs reads the configuration file from the given path
-func ReadConfigs(configPath string) (*Configs, error) {
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("config file %s does not exist", configPath)
-	}
+// Effective returns a copy of the configuration with every default filled in,
+// as the commands see it
+func (c *Configs) Effective() *Configs {
+	redactSecrets := c.ShouldRedactSecrets()
+	redactJSONValues := c.ShouldRedactJSONValues()
 
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
+	effective := &Configs{
+		Environments:     make(map[string]EnvironmentConfig, len(c.Environments)),
+		RedactedKeys:     c.GetRedactedKeys(),
+		RedactSecrets:    &redactSecrets,
+		RedactJSONValues: &redactJSONValues,
+		SensitiveKeys:    c.GetSensitiveKeys(),
 
-	var configs Configs
-	if err := json.Unmarshal(data, &configs); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		RedactionPolicies: c.RedactionPolicies,
+		Promotion: &PromotionConfig{
+			Chain:        c.PromotionChain(),
+			PathTemplate: c.defaultPathTemplate(),
+			Suffixes:     c.PathSuffixes(),
+		},
 	}
 
-	if configs.Environments == nil || len(configs.Environments) == 0 {
-		return nil, fmt.Errorf("no environments defined in config file")
+	for name, env := range c.Environments {
+		if env.Store == "" {
+			env.Store = StoreVault
+		}
+		effective.Environments[name] = env
 	}
 
-	return &configs, nil
+	return effective
 }
 
 // GetEnvironmentConfig returns the configuration for the given environment
@@ -98,37 +198,163 @@ func (c *Configs) GetEnvironmentConfig(env string) (*EnvironmentConfig, error) {
 		return nil, fmt.Errorf("environment %s not found in config", env)
 	}
 
-	if config.URL == "" && config.Store == "vault" {
-		return nil, fmt.Errorf("URL not specified for vault environment %s", env)
+	if err := config.validateTransport(); err != nil {
+		return nil, fmt.Errorf("invalid transport settings for environment %s: %w", env, err)
 	}
 
-	if config.TokenEnv == "" && config.Store == "vault" {
-		return nil, fmt.Errorf("token_env not specified for vault environment %s", env)
+	if config.KVVersion != "" && config.KVVersion != "1" && config.KVVersion != "2" {
+		return nil, fmt.Errorf("'kv_version' must be \"1\" or \"2\" for environment %s", env)
 	}
 
-	// Validate the store type
-	if config.Store != "" && config.Store != "vault" && config.Store != "awssecretsmanager" {
+	// Validate the store type and the fields it requires
+	switch config.Store {
+	case "":
+		// Environments without a store type are opened as Vault
+		if err := config.validateVaultAuth(env); err != nil {
+			return nil, err
+		}
+	case StoreVault:
+		if config.URL == "" {
+			return nil, fmt.Errorf("URL not specified for vault environment %s", env)
+		}
+
+		if err := config.validateVaultAuth(env); err != nil {
+			return nil, err
+		}
+
+		if config.UsesTokenAuth() {
+			if err := config.validateTokenSource(); err != nil {
+				return nil, fmt.Errorf("%w for vault environment %s", err, env)
+			}
+		}
+	case StoreAWSSecretsManager:
+		// AWS Secrets Manager requires a role ARN or a profile
+		if err := config.validateAWS(); err != nil {
+			return nil, fmt.Errorf("invalid AWS Secrets Manager environment %s: %w", env, err)
+		}
+	case StoreAWSSSM:
+		// SSM Parameter Store uses the same credentials as Secrets Manager
+		if err := config.validateAWS(); err != nil {
+			return nil, fmt.Errorf("invalid AWS SSM environment %s: %w", env, err)
+		}
+	case StoreAzureKeyVault:
+		if config.VaultURI == "" {
+			return nil, fmt.Errorf("'vault_uri' is required for Azure Key Vault environment %s", env)
+		}
+
+		if config.TenantID == "" || config.ClientID == "" || config.ClientSecretEnv == "" {
+			return nil, fmt.Errorf("'tenant_id', 'client_id' and 'client_secret_env' are required for Azure Key Vault environment %s", env)
+		}
+	case StoreGCPSecretManager:
+		if config.ProjectID == "" {
+			return nil, fmt.Errorf("'project_id' is required for GCP Secret Manager environment %s", env)
+		}
+	case StoreKubernetes:
+		// Everything has a default, the kubeconfig is checked when connecting
+	case StoreFile:
+		if config.Directory == "" || config.KeyEnv == "" {
+			return nil, fmt.Errorf("'directory' and 'key_env' are required for file environment %s", env)
+		}
+	case StoreLocalFile:
+		// Paths are resolved against the current directory unless 'directory' is set
+	case StoreConsul:
+		if config.URL == "" {
+			return nil, fmt.Errorf("'url' is required for Consul environment %s", env)
+		}
+	case StorePlugin:
+		if config.Plugin == "" {
+			return nil, fmt.Errorf("'plugin' is required for plugin environment %s", env)
+		}
+	default:
 		return nil, fmt.Errorf("unsupported store type: %s", config.Store)
 	}
 
-	// AWS Secrets Manager requires a role ARN
-	if config.Store == "awssecretsmanager" && config.Role == "" {
-		return nil, fmt.Errorf("'role' is required for AWS Secrets Manager environments")
+	return &config, nil
+}
+
+// UsesTokenAuth reports whether the environment logs in to Vault with a static token
+func (e *EnvironmentConfig) UsesTokenAuth() bool {
+	return e.Auth == nil || e.Auth.Method == AuthToken
+}
+
+// validateVaultAuth checks the auth block of a Vault environment, if it has one
+func (e *EnvironmentConfig) validateVaultAuth(env string) error {
+	if e.Auth == nil {
+		return nil
+	}
+
+	if err := e.Auth.Validate(); err != nil {
+		return fmt.Errorf("invalid auth for vault environment %s: %w", env, err)
 	}
+	return nil
+}
 
-	return &config, nil
+// validateAWS checks the credential settings of an AWS environment. A role is
+// needed unless a profile or a local endpoint provides the credentials.
+func (e *EnvironmentConfig) validateAWS() error {
+	if e.Role == "" && e.Profile == "" && e.Endpoint == "" {
+		return fmt.Errorf("'role' or 'profile' is required")
+	}
+
+	if e.Role == "" && (e.ExternalID != "" || e.MFASerial != "" || e.WebIdentityTokenFile != "" || e.SessionDuration != "") {
+		return fmt.Errorf("'external_id', 'mfa_serial', 'web_identity_token_file' and 'session_duration' require 'role'")
+	}
+
+	if e.WebIdentityTokenFile != "" && (e.ExternalID != "" || e.MFASerial != "") {
+		return fmt.Errorf("'external_id' and 'mfa_serial' can't be used with 'web_identity_token_file'")
+	}
+
+	if e.SessionDuration != "" {
+		if _, err := time.ParseDuration(e.SessionDuration); err != nil {
+			return fmt.Errorf("invalid 'session_duration' %q: %w", e.SessionDuration, err)
+		}
+	}
+
+	return nil
+}
+
+// validateTransport checks the TLS and HTTP transport settings
+func (e *EnvironmentConfig) validateTransport() error {
+	if (e.ClientCert == "") != (e.ClientKey == "") {
+		return fmt.Errorf("'client_cert' and 'client_key' must be set together")
+	}
+
+	if e.Proxy != "" {
+		if _, err := url.Parse(e.Proxy); err != nil {
+			return fmt.Errorf("invalid 'proxy' %q: %w", e.Proxy, err)
+		}
+	}
+
+	if _, err := e.GetTimeout(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetTimeout returns the request timeout, or 0 when none is configured
+func (e *EnvironmentConfig) GetTimeout() (time.Duration, error) {
+	if e.Timeout == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(e.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid 'timeout' %q, expected a duration such as 30s", e.Timeout)
+	}
+	return timeout, nil
 }
 
-// GetVaultToken retrieves the Vault token from the environment variable
-func (e *EnvironmentConfig) GetVaultToken() (string, error) {
-	if e.TokenEnv == "" {
-		return "", fmt.Errorf("token_env not specified in the environment config")
+// GetAzureClientSecret retrieves the Azure client secret from the environment variable
+func (e *EnvironmentConfig) GetAzureClientSecret() (string, error) {
+	if e.ClientSecretEnv == "" {
+		return "", fmt.Errorf("client_secret_env not specified in the environment config")
 	}
 
-	token := os.Getenv(e.TokenEnv)
-	if token == "" {
-		return "", fmt.Errorf("environment variable %s not set or empty", e.TokenEnv)
+	secret := os.Getenv(e.ClientSecretEnv)
+	if secret == "" {
+		return "", fmt.Errorf("environment variable %s not set or empty", e.ClientSecretEnv)
 	}
 
-	return strings.TrimSpace(token), nil
+	return strings.TrimSpace(secret), nil
 }
@@ -0,0 +1,461 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the environment variables that override config fields,
+// e.g. VAULT_PROMOTER_REDACT_SECRETS or VAULT_PROMOTER_ENVIRONMENTS__PROD__URL
+const EnvPrefix = "VAULT_PROMOTER_"
+
+// SystemConfigDir holds the config shared by every user of a machine or image
+const SystemConfigDir = "/etc/vault-promoter"
+
+// ProjectConfigName is the config file searched for in the working directory and its parents
+const ProjectConfigName = ".vaultconfigs"
+
+// TrustConfigEnv, when true, allows command fields in config files that
+// belong to another user
+const TrustConfigEnv = EnvPrefix + "TRUST_CONFIG"
+
+// configExtensions are tried in order when looking for a config file
+var configExtensions = []string{".yaml", ".yml", ".toml", ".json"}
+
+// tokenSourceFields are the ways an environment gets its Vault token. A layer
+// that sets one of them replaces the others from lower layers, so a user file
+// can swap the team's token_env for a token_file.
+var tokenSourceFields = []string{"token_env", "token_file", "token_helper", "token_command", "auth"}
+
+// commandFields make vault-promoter run a program, directly or through the
+// exec credentials of a kubeconfig. They are only read from files owned by
+// the current user or root, so a config planted in a shared directory can't
+// run code as whoever happens to work below it.
+var commandFields = []string{"token_helper", "token_command", "plugin", "plugin_args", "kubeconfig"}
+
+// interpolation matches ${VAR} and ${VAR:-default}; $${ escapes a literal ${
+var interpolation = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// UserConfigDir returns $XDG_CONFIG_HOME/vault-promoter, or ~/.config/vault-promoter
+func UserConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "vault-promoter")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "vault-promoter")
+}
+
+// LoadConfigs reads the system, user and project config files, merges them
+// with later layers taking precedence, and applies VAULT_PROMOTER_*
+// overrides on top. configPath replaces the project file when set; otherwise
+// .vaultconfigs is searched for from the working directory up to the
+// repository root or the home directory.
+func LoadConfigs(configPath string) (*Configs, error) {
+	files, err := ConfigFiles(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, warnings, err := mergeConfigFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, applyEnvOverrides(raw, os.Environ())...)
+
+	configs, err := decodeConfigs(raw, strings.Join(files, ", "))
+	if err != nil {
+		return nil, err
+	}
+	configs.Warnings = append(warnings, configs.bareKeyRuleWarnings()...)
+	configs.Sources = files
+
+	return configs, nil
+}
+
+// ConfigFiles lists the config files LoadConfigs reads, lowest precedence first
+func ConfigFiles(configPath string) ([]string, error) {
+	var files []string
+
+	if file := findConfigFile(SystemConfigDir, "config"); file != "" {
+		files = append(files, file)
+	}
+
+	if dir := UserConfigDir(); dir != "" {
+		if file := findConfigFile(dir, "config"); file != "" {
+			files = append(files, file)
+		}
+	}
+
+	if configPath != "" {
+		if _, err := os.Stat(configPath); err != nil {
+			return nil, fmt.Errorf("config file %s does not exist", configPath)
+		}
+		files = append(files, configPath)
+	} else if file := findProjectConfig(); file != "" {
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config file found: create %s, %s/config.yaml or %s/config.yaml, or pass --config",
+			ProjectConfigName, UserConfigDir(), SystemConfigDir)
+	}
+
+	return files, nil
+}
+
+// findConfigFile returns the first of dir/name, dir/name.yaml, ... that exists
+func findConfigFile(dir, name string) string {
+	for _, ext := range append([]string{""}, configExtensions...) {
+		file := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// findProjectConfig looks for .vaultconfigs in the working directory and its
+// parents, up to the root of the git repository or the home directory
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		if resolved, err := filepath.EvalSymlinks(home); err == nil {
+			home = resolved
+		}
+	}
+
+	for _, dir := range projectSearchDirs(dir, home) {
+		if file := findConfigFile(dir, ProjectConfigName); file != "" {
+			return file
+		}
+	}
+	return ""
+}
+
+// projectSearchDirs lists dir and its parents up to the first one that is a
+// repository root or home. Outside of both only dir itself is searched, so
+// a file left higher up in a shared tree such as /tmp is never picked up.
+func projectSearchDirs(dir, home string) []string {
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if dir == home || isRepositoryRoot(dir) {
+			return dirs
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dirs[:1]
+		}
+		dir = parent
+	}
+}
+
+// isRepositoryRoot reports whether dir holds a .git directory, or the .git
+// file of a worktree or submodule
+func isRepositoryRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// mergeConfigFiles reads, interpolates and merges files in order. Unknown
+// fields and unset variables are reported against the file they appear in.
+func mergeConfigFiles(files []string) (map[string]interface{}, []string, error) {
+	merged := make(map[string]interface{})
+	var warnings []string
+
+	for _, file := range files {
+		raw, err := readConfigFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := checkCommandFields(file, raw); err != nil {
+			return nil, nil, err
+		}
+
+		for _, field := range unknownFields(raw, reflect.TypeOf(Configs{}), "") {
+			warnings = append(warnings, fmt.Sprintf("%s: unknown field %s", file, field))
+		}
+		for _, name := range interpolate(raw) {
+			warnings = append(warnings, fmt.Sprintf("%s: ${%s} is not set", file, name))
+		}
+
+		mergeLayer(merged, raw)
+	}
+
+	return merged, warnings, nil
+}
+
+// checkCommandFields refuses command fields from a file owned by another
+// user, unless VAULT_PROMOTER_TRUST_CONFIG is set
+func checkCommandFields(file string, raw map[string]interface{}) error {
+	field := findCommandField(raw)
+	if field == "" {
+		return nil
+	}
+
+	owned, err := ownedByCurrentUser(file)
+	if err != nil {
+		return fmt.Errorf("failed to check the owner of %s: %w", file, err)
+	}
+	if owned {
+		return nil
+	}
+
+	if trust, _ := strconv.ParseBool(os.Getenv(TrustConfigEnv)); trust {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s runs a command, but the file belongs to another user; set it in a file of your own, or set %s=true if you trust this one",
+		file, field, TrustConfigEnv)
+}
+
+// findCommandField returns the first command field set in raw, as
+// "environments.<name>.<field>", or "" when there is none
+func findCommandField(raw map[string]interface{}) string {
+	environments, _ := raw["environments"].(map[string]interface{})
+
+	names := make([]string, 0, len(environments))
+	for name := range environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		env, _ := environments[name].(map[string]interface{})
+		for _, field := range commandFields {
+			if _, ok := env[field]; ok {
+				return fmt.Sprintf("environments.%s.%s", name, field)
+			}
+		}
+	}
+	return ""
+}
+
+// mergeLayer merges src into dst. Tables are merged key by key, anything
+// else, including lists, is replaced.
+func mergeLayer(dst, src map[string]interface{}) {
+	if environments, ok := src["environments"].(map[string]interface{}); ok {
+		existing, _ := dst["environments"].(map[string]interface{})
+		for name, env := range environments {
+			envDst, _ := existing[name].(map[string]interface{})
+			envSrc, _ := env.(map[string]interface{})
+			for _, field := range tokenSourceFields {
+				if _, ok := envSrc[field]; ok {
+					clearTokenSources(envDst)
+					break
+				}
+			}
+		}
+	}
+
+	mergeMaps(dst, src)
+}
+
+// clearTokenSources removes every token source from an environment
+func clearTokenSources(env map[string]interface{}) {
+	for _, field := range tokenSourceFields {
+		delete(env, field)
+	}
+}
+
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// interpolate expands ${VAR} and ${VAR:-default} in every string value of raw,
+// returning the variables that were unset and had no default
+func interpolate(raw interface{}) []string {
+	var unset []string
+
+	var expand func(value interface{}) interface{}
+	expand = func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			return interpolation.ReplaceAllStringFunc(v, func(match string) string {
+				if match == "$${" {
+					return "${"
+				}
+
+				groups := interpolation.FindStringSubmatch(match)
+				if value, ok := os.LookupEnv(groups[1]); ok && value != "" {
+					return value
+				}
+				if strings.Contains(match, ":-") {
+					return groups[2]
+				}
+				unset = append(unset, groups[1])
+				return ""
+			})
+		case map[string]interface{}:
+			for key, item := range v {
+				v[key] = expand(item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				v[i] = expand(item)
+			}
+		}
+		return value
+	}
+
+	expand(raw)
+	sort.Strings(unset)
+	return unset
+}
+
+// applyEnvOverrides sets the fields named by VAULT_PROMOTER_* variables in
+// raw. Double underscores separate levels, and names are matched ignoring
+// case, so VAULT_PROMOTER_ENVIRONMENTS__PROD__TOKEN_FILE sets token_file of
+// the prod environment. Variables that don't name a field are ignored, since
+// the prefix is also used for variables like key_env.
+func applyEnvOverrides(raw map[string]interface{}, environ []string) []string {
+	sort.Strings(environ)
+
+	var warnings []string
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+
+		segments := strings.Split(strings.TrimPrefix(name, EnvPrefix), "__")
+		keys, fieldType, ok := overrideField(raw, segments)
+		if !ok {
+			continue
+		}
+
+		parsed, err := parseOverride(value, fieldType)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		parent := raw
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := parent[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				parent[key] = next
+			}
+			parent = next
+		}
+
+		last := keys[len(keys)-1]
+		if len(keys) == 3 && keys[0] == "environments" {
+			for _, field := range tokenSourceFields {
+				if field == last {
+					clearTokenSources(parent)
+				}
+			}
+		}
+		parent[last] = parsed
+	}
+
+	return warnings
+}
+
+// overrideField resolves the segments of a variable name to config keys and
+// the type of the field they name. Map keys match existing entries such as
+// environment names ignoring case, with "_" standing for "-".
+func overrideField(raw map[string]interface{}, segments []string) ([]string, reflect.Type, bool) {
+	t := reflect.TypeOf(Configs{})
+	current := raw
+	keys := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if segment == "" {
+			return nil, nil, false
+		}
+
+		var key string
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := jsonFields(t)[strings.ToLower(segment)]
+			if !ok {
+				return nil, nil, false
+			}
+			key, t = strings.ToLower(segment), field.Type
+		case reflect.Map:
+			key = strings.ToLower(segment)
+			for existing := range current {
+				if strings.EqualFold(strings.ReplaceAll(existing, "-", "_"), segment) {
+					key = existing
+					break
+				}
+			}
+			t = t.Elem()
+		default:
+			return nil, nil, false
+		}
+
+		keys = append(keys, key)
+		current, _ = current[key].(map[string]interface{})
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return keys, t, len(keys) > 0
+}
+
+// parseOverride converts a variable's value to the type of the field it sets.
+// Lists are comma-separated.
+func parseOverride(value string, t reflect.Type) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", value)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", value)
+		}
+		return i, nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			break
+		}
+		items := []interface{}{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("%s fields can't be set from the environment, set their fields one by one", t.Kind())
+}
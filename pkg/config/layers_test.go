@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProjectSearchDirs(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home", "me")
+	repo := filepath.Join(home, "src", "repo")
+	outside := filepath.Join(root, "srv", "repo")
+	for _, dir := range []string{filepath.Join(repo, ".git"), filepath.Join(repo, "app", "dev"), filepath.Join(outside, "app"), filepath.Join(root, "tmp", "work")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A worktree or submodule has a .git file instead of a directory
+	if err := os.WriteFile(filepath.Join(outside, ".git"), []byte("gitdir: /elsewhere\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want []string
+	}{
+		{
+			name: "stops at the repository root",
+			dir:  filepath.Join(repo, "app", "dev"),
+			want: []string{filepath.Join(repo, "app", "dev"), filepath.Join(repo, "app"), repo},
+		},
+		{
+			name: "stops at home outside of a repository",
+			dir:  filepath.Join(home, "src"),
+			want: []string{filepath.Join(home, "src"), home},
+		},
+		{
+			name: "stops at a worktree root",
+			dir:  filepath.Join(outside, "app"),
+			want: []string{filepath.Join(outside, "app"), outside},
+		},
+		{
+			name: "only the working directory outside of both",
+			dir:  filepath.Join(root, "tmp", "work"),
+			want: []string{filepath.Join(root, "tmp", "work")},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := projectSearchDirs(tt.dir, home); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: projectSearchDirs(%s) = %v, want %v", tt.name, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestFindCommandField(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want string
+	}{
+		{
+			name: "no environments",
+			raw:  map[string]interface{}{"redact_secrets": true},
+			want: "",
+		},
+		{
+			name: "no command fields",
+			raw: map[string]interface{}{"environments": map[string]interface{}{
+				"dev": map[string]interface{}{"url": "https://vault-dev", "token_env": "VAULT_TOKEN"},
+			}},
+			want: "",
+		},
+		{
+			name: "token command",
+			raw: map[string]interface{}{"environments": map[string]interface{}{
+				"dev":  map[string]interface{}{"token_env": "VAULT_TOKEN"},
+				"prod": map[string]interface{}{"token_command": "op read op://vault/prod/token"},
+			}},
+			want: "environments.prod.token_command",
+		},
+		{
+			name: "plugin arguments alone",
+			raw: map[string]interface{}{"environments": map[string]interface{}{
+				"ext": map[string]interface{}{"plugin_args": []interface{}{"--insecure"}},
+			}},
+			want: "environments.ext.plugin_args",
+		},
+		{
+			name: "kubeconfig",
+			raw: map[string]interface{}{"environments": map[string]interface{}{
+				"k8s": map[string]interface{}{"kubeconfig": "/tmp/kubeconfig"},
+			}},
+			want: "environments.k8s.kubeconfig",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := findCommandField(tt.raw); got != tt.want {
+			t.Errorf("%s: findCommandField() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCommandFieldsOwnFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".vaultconfigs")
+	if err := os.WriteFile(file, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := map[string]interface{}{"environments": map[string]interface{}{
+		"prod": map[string]interface{}{"token_helper": "vault-token-helper"},
+	}}
+	if err := checkCommandFields(file, raw); err != nil {
+		t.Errorf("checkCommandFields() on a file of the current user error = %v", err)
+	}
+}
+
+func TestCheckCommandFieldsOtherUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("giving a file to another user needs root")
+	}
+
+	file := filepath.Join(t.TempDir(), ".vaultconfigs")
+	if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(file, 65534, 65534); err != nil {
+		t.Skipf("failed to give the file to another user: %v", err)
+	}
+
+	raw := map[string]interface{}{"environments": map[string]interface{}{
+		"prod": map[string]interface{}{"url": "https://vault-prod"},
+	}}
+	if err := checkCommandFields(file, raw); err != nil {
+		t.Errorf("checkCommandFields() without command fields error = %v", err)
+	}
+
+	raw["environments"].(map[string]interface{})["prod"].(map[string]interface{})["token_command"] = "cat /tmp/token"
+	if err := checkCommandFields(file, raw); err == nil {
+		t.Errorf("checkCommandFields() should refuse token_command from another user's file")
+	}
+
+	t.Setenv(TrustConfigEnv, "true")
+	if err := checkCommandFields(file, raw); err != nil {
+		t.Errorf("checkCommandFields() with %s error = %v", TrustConfigEnv, err)
+	}
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// fieldAliases maps field names that are commonly written by mistake to the real ones
+var fieldAliases = map[string]string{
+	"hide_secrets": "redact_secrets",
+	"token":        "token_env",
+	"address":      "url",
+	"vault_addr":   "url",
+}
+
+// ReadConfigs reads the configuration file from the given path alone, without
+// the layers and overrides of LoadConfigs. JSON, YAML and TOML are accepted;
+// unknown fields are reported in Warnings, and every environment is validated
+// before the file is used.
+func ReadConfigs(configPath string) (*Configs, error) {
+	raw, warnings, err := mergeConfigFiles([]string{configPath})
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := decodeConfigs(raw, configPath)
+	if err != nil {
+		return nil, err
+	}
+	configs.Warnings = append(warnings, configs.bareKeyRuleWarnings()...)
+	configs.Sources = []string{configPath}
+
+	return configs, nil
+}
+
+// configFormat picks the format from the file extension. Files without a
+// known extension, such as .vaultconfigs, are JSON if they start with "{"
+// and YAML otherwise.
+func configFormat(configPath string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// readConfigFile parses a config file of any supported format into nested maps
+func readConfigFile(configPath string) (map[string]interface{}, error) {
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("config file %s does not exist", configPath)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch format := configFormat(configPath, data); format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &raw)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &raw)
+	case FormatTOML:
+		raw, err = parseTOML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+
+	return raw, nil
+}
+
+// decodeConfigs turns parsed config data into Configs through the JSON field names,
+// so every format accepts exactly the same fields
+func decodeConfigs(raw map[string]interface{}, source string) (*Configs, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", source, err)
+	}
+
+	var configs Configs
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", source, err)
+	}
+
+	if len(configs.Environments) == 0 {
+		return nil, fmt.Errorf("no environments defined in config file")
+	}
+
+	if err := configs.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &configs, nil
+}
+
+// Validate checks every environment, reporting all problems at once
+func (c *Configs) Validate() error {
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		if _, err := c.GetEnvironmentConfig(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if _, err := ParseKeyRules(c.RedactedKeys); err != nil {
+		problems = append(problems, fmt.Sprintf("redacted_keys: %v", err))
+	}
+	if _, err := ParseKeyRules(c.SensitiveKeys); err != nil {
+		problems = append(problems, fmt.Sprintf("sensitive_keys: %v", err))
+	}
+
+	problems = append(problems, c.validatePromotion()...)
+
+	for i, policy := range c.RedactionPolicies {
+		if err := policy.validate(c); err != nil {
+			problems = append(problems, fmt.Sprintf("redaction policy %d: %v", i+1, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// jsonFields returns the JSON field names of a struct type
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// unknownFields lists the keys of raw that t has no field for, as dotted paths,
+// with a suggestion when the key looks like a known field
+func unknownFields(raw interface{}, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			unknown = append(unknown, unknownFields(values[key], t.Elem(), path+key+".")...)
+		}
+	case reflect.Struct:
+		fields := jsonFields(t)
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			field, ok := fields[key]
+			if !ok {
+				unknown = append(unknown, path+key+suggestField(key, fields))
+				continue
+			}
+			unknown = append(unknown, unknownFields(values[key], field.Type, path+key+".")...)
+		}
+	}
+
+	return unknown
+}
+
+// suggestField returns " (did you mean x?)" when key is a known alias or a near typo of a field
+func suggestField(key string, fields map[string]reflect.StructField) string {
+	if alias, ok := fieldAliases[key]; ok {
+		if _, known := fields[alias]; known {
+			return fmt.Sprintf(" (did you mean %s?)", alias)
+		}
+	}
+
+	best, bestDistance := "", 3
+	for name := range fields {
+		if distance := editDistance(key, name); distance < bestDistance || distance == bestDistance && name < best {
+			best, bestDistance = name, distance
+		}
+	}
+	if best != "" && bestDistance < 3 {
+		return fmt.Sprintf(" (did you mean %s?)", best)
+	}
+	return ""
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByCurrentUser reports whether file belongs to the current user or to root
+func ownedByCurrentUser(file string) (bool, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true, nil
+	}
+	return stat.Uid == uint32(os.Getuid()) || stat.Uid == 0, nil
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Path template placeholders
+const (
+	PlaceholderApp    = "{app}"
+	PlaceholderEnv    = "{env}"
+	PlaceholderSuffix = "{suffix}"
+)
+
+// DefaultPromotionChain is the promotion order used when the config defines none
+var DefaultPromotionChain = []string{"dev", "uat", "prod"}
+
+// DefaultPathTemplate is the app/env/suffix layout of the opinionated mode
+const DefaultPathTemplate = PlaceholderApp + "/" + PlaceholderEnv + "/" + PlaceholderSuffix
+
+// DefaultPathSuffixes are the accepted last segments of an app path
+var DefaultPathSuffixes = []string{"config", "configs", "secret", "secrets"}
+
+// placeholder matches anything written like a placeholder in a path template
+var placeholder = regexp.MustCompile(`\{[^}]*\}`)
+
+// PromotionConfig describes the environments secrets are promoted through,
+// in order, and where each app keeps its secrets
+type PromotionConfig struct {
+	Chain        []string `json:"chain,omitempty"`         // environments in promotion order, e.g. dev, qa, perf, prod
+	PathTemplate string   `json:"path_template,omitempty"` // e.g. "{app}/{env}/{suffix}", stores may override it
+	Suffixes     []string `json:"suffixes,omitempty"`      // accepted values of {suffix}
+}
+
+// PromotionChain returns the environments in promotion order
+func (c *Configs) PromotionChain() []string {
+	if c.Promotion == nil || len(c.Promotion.Chain) == 0 {
+		return DefaultPromotionChain
+	}
+	return c.Promotion.Chain
+}
+
+// PathSuffixes returns the accepted values of {suffix}
+func (c *Configs) PathSuffixes() []string {
+	if c.Promotion == nil || len(c.Promotion.Suffixes) == 0 {
+		return DefaultPathSuffixes
+	}
+	return c.Promotion.Suffixes
+}
+
+func (c *Configs) defaultPathTemplate() string {
+	if c.Promotion == nil || c.Promotion.PathTemplate == "" {
+		return DefaultPathTemplate
+	}
+	return c.Promotion.PathTemplate
+}
+
+// PathTemplate returns the path template of the store configured as instanceName
+func (c *Configs) PathTemplate(instanceName string) string {
+	if env, ok := c.Environments[instanceName]; ok && env.PathTemplate != "" {
+		return env.PathTemplate
+	}
+	return c.defaultPathTemplate()
+}
+
+// NextEnvironment returns the environment env is promoted to
+func (c *Configs) NextEnvironment(env string) (string, error) {
+	chain := c.PromotionChain()
+	for i, name := range chain {
+		if name != env {
+			continue
+		}
+		if i == len(chain)-1 {
+			return "", fmt.Errorf("environment %s is the last of the promotion chain", env)
+		}
+		return chain[i+1], nil
+	}
+	return "", fmt.Errorf("environment %s is not in the promotion chain (%s)", env, strings.Join(chain, ", "))
+}
+
+// IsSecretSuffix reports whether a path suffix names its secrets as secrets,
+// such as "secrets", so everything under it is redacted by env-compare
+func IsSecretSuffix(suffix string) bool {
+	return strings.Contains(strings.ToLower(suffix), "secret")
+}
+
+// UsesSuffix reports whether the path template of instanceName contains {suffix}
+func (c *Configs) UsesSuffix(instanceName string) bool {
+	return strings.Contains(c.PathTemplate(instanceName), PlaceholderSuffix)
+}
+
+// SecretPath builds the path of an app's secret in the store configured as
+// instanceName, after checking env, and suffix when the template uses it,
+// against the promotion settings
+func (c *Configs) SecretPath(instanceName, app, env, suffix string) (string, error) {
+	inChain := false
+	for _, name := range c.PromotionChain() {
+		if name == env {
+			inChain = true
+			break
+		}
+	}
+	if !inChain {
+		return "", fmt.Errorf("environment %s is not in the promotion chain (%s)", env, strings.Join(c.PromotionChain(), ", "))
+	}
+
+	// Templates without a suffix, such as "{env}/{app}", ignore it
+	template := c.PathTemplate(instanceName)
+	if c.UsesSuffix(instanceName) {
+		validSuffix := false
+		for _, name := range c.PathSuffixes() {
+			if name == suffix {
+				validSuffix = true
+				break
+			}
+		}
+		if !validSuffix {
+			return "", fmt.Errorf("invalid path suffix: %s, must be one of: %s", suffix, strings.Join(c.PathSuffixes(), ", "))
+		}
+	}
+
+	replacer := strings.NewReplacer(PlaceholderApp, app, PlaceholderEnv, env, PlaceholderSuffix, suffix)
+	return replacer.Replace(template), nil
+}
+
+// validatePromotion checks the chain and every path template
+func (c *Configs) validatePromotion() []string {
+	var problems []string
+
+	seen := make(map[string]bool)
+	for _, name := range c.PromotionChain() {
+		if name == "" {
+			problems = append(problems, "promotion chain has an empty environment name")
+			continue
+		}
+		if seen[name] {
+			problems = append(problems, fmt.Sprintf("environment %s appears twice in the promotion chain", name))
+		}
+		seen[name] = true
+	}
+
+	if err := validatePathTemplate(c.defaultPathTemplate()); err != nil {
+		problems = append(problems, fmt.Sprintf("promotion: %v", err))
+	}
+
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if template := c.Environments[name].PathTemplate; template != "" {
+			if err := validatePathTemplate(template); err != nil {
+				problems = append(problems, fmt.Sprintf("environment %s: %v", name, err))
+			}
+		}
+	}
+
+	return problems
+}
+
+// validatePathTemplate checks that a template places the app and the
+// environment and uses no unknown placeholders
+func validatePathTemplate(template string) error {
+	for _, required := range []string{PlaceholderApp, PlaceholderEnv} {
+		if !strings.Contains(template, required) {
+			return fmt.Errorf("path template %q must contain %s", template, required)
+		}
+	}
+
+	for _, name := range placeholder.FindAllString(template, -1) {
+		if name != PlaceholderApp && name != PlaceholderEnv && name != PlaceholderSuffix {
+			return fmt.Errorf("path template %q has unknown placeholder %s, use %s, %s or %s",
+				template, name, PlaceholderApp, PlaceholderEnv, PlaceholderSuffix)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// RedactionPolicy overrides the global redaction settings for the secrets of
+// some environments and paths. Unset fields keep the value they had before
+// the policy applied.
+type RedactionPolicy struct {
+	Environments     []string `json:"environments,omitempty"` // environment names, every environment when empty
+	Paths            []string `json:"paths,omitempty"`        // path globs such as "*/prod/secrets/**", every path when empty
+	RedactSecrets    *bool    `json:"redact_secrets,omitempty"`
+	RedactJSONValues *bool    `json:"redact_json_values,omitempty"`
+	RedactAll        *bool    `json:"redact_all,omitempty"` // redact every value, whatever the key name
+	RedactedKeys     []string `json:"redacted_keys,omitempty"`
+}
+
+var redactAll = true
+
+// secretPathsPolicy is the name under which the paths given to
+// WithSecretPaths show up in explanations
+const secretPathsPolicy = "the secret suffix of env-compare"
+
+// Redaction is the redaction settings that apply to one secret
+type Redaction struct {
+	RedactSecrets    bool
+	RedactJSONValues bool
+	RedactAll        bool
+	RedactedKeys     []string
+	Rules            KeyRules // parsed RedactedKeys
+
+	Policies      []string // the policies that matched, in order, for explanations
+	KeysFrom      string   // where RedactedKeys come from, for explanations
+	RedactAllFrom string   // the policy that set RedactAll, for explanations
+}
+
+// KeyDecision tells whether the value of a key is redacted and why
+type KeyDecision struct {
+	Redacted bool
+	Reason   string
+	Rule     *KeyRule // the rule that decided, if any
+}
+
+// RedactionFor resolves the redaction settings of the secret at secretPath in
+// the given environment: the global settings, then every matching policy in
+// order, so later policies win
+func (c *Configs) RedactionFor(env, secretPath string) *Redaction {
+	redaction := &Redaction{
+		RedactSecrets:    c.ShouldRedactSecrets(),
+		RedactJSONValues: c.ShouldRedactJSONValues(),
+		RedactedKeys:     c.GetRedactedKeys(),
+		KeysFrom:         "redacted_keys",
+	}
+	if len(c.RedactedKeys) == 0 {
+		redaction.KeysFrom = "the default redacted keys"
+	}
+
+	secretPath, _ = store.SplitVersion(secretPath)
+	if len(c.secretPaths) > 0 {
+		redaction.apply(&RedactionPolicy{Paths: c.secretPaths, RedactAll: &redactAll}, secretPathsPolicy, env, secretPath)
+	}
+	for i := range c.RedactionPolicies {
+		redaction.apply(&c.RedactionPolicies[i], fmt.Sprintf("redaction policy %d", i+1), env, secretPath)
+	}
+
+	// Invalid entries are reported by Validate, so the valid ones still apply
+	for _, entry := range redaction.RedactedKeys {
+		if rule, err := ParseKeyRule(entry); err == nil {
+			redaction.Rules = append(redaction.Rules, rule)
+		}
+	}
+
+	return redaction
+}
+
+// WithSecretPaths returns a copy of the config that redacts every value of
+// the secrets at paths before its own policies apply, so one of them can
+// still set redact_all back to false. env-compare uses it for the paths it
+// builds from a suffix that names them as secrets.
+func (c *Configs) WithSecretPaths(paths ...string) *Configs {
+	scoped := *c
+	scoped.secretPaths = nil
+	for _, secretPath := range paths {
+		scoped.secretPaths = append(scoped.secretPaths, escapeGlob(secretPath))
+	}
+	return &scoped
+}
+
+// escapeGlob quotes the characters path.Match treats specially, so a path
+// only matches itself
+func escapeGlob(pattern string) string {
+	var escaped strings.Builder
+	for _, r := range pattern {
+		if strings.ContainsRune(`*?[\`, r) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// apply overrides the settings with those of policy when it matches
+func (r *Redaction) apply(policy *RedactionPolicy, name, env, secretPath string) {
+	if !policy.Matches(env, secretPath) {
+		return
+	}
+	r.Policies = append(r.Policies, name)
+
+	if policy.RedactSecrets != nil {
+		r.RedactSecrets = *policy.RedactSecrets
+	}
+	if policy.RedactJSONValues != nil {
+		r.RedactJSONValues = *policy.RedactJSONValues
+	}
+	if policy.RedactAll != nil {
+		r.RedactAll = *policy.RedactAll
+		r.RedactAllFrom = name
+	}
+	if len(policy.RedactedKeys) > 0 {
+		r.RedactedKeys = policy.RedactedKeys
+		r.KeysFrom = "redacted_keys of " + name
+	}
+}
+
+// Decide tells whether the value of key is redacted. flagged is set when the
+// store itself marks the key as sensitive. Allow-list rules win over
+// everything but disabled redaction.
+func (r *Redaction) Decide(key string, flagged bool) KeyDecision {
+	if !r.RedactSecrets {
+		return KeyDecision{Reason: "redaction is disabled"}
+	}
+
+	rule := r.Rules.Match(key)
+	switch {
+	case rule != nil && rule.Allow:
+		return KeyDecision{Reason: fmt.Sprintf("%s in %s", rule, r.KeysFrom), Rule: rule}
+	case r.RedactAll:
+		return KeyDecision{Redacted: true, Reason: "redact_all is set by " + r.RedactAllFrom}
+	case flagged:
+		return KeyDecision{Redacted: true, Reason: "the store marks it as sensitive"}
+	case rule != nil:
+		return KeyDecision{Redacted: true, Reason: fmt.Sprintf("%s in %s", rule, r.KeysFrom), Rule: rule}
+	}
+
+	return KeyDecision{Reason: fmt.Sprintf("no rule in %s matches", r.KeysFrom)}
+}
+
+// RedactionDisabled reports whether any environment or path shows secrets in plain text
+func (c *Configs) RedactionDisabled() bool {
+	if !c.ShouldRedactSecrets() {
+		return true
+	}
+	for _, policy := range c.RedactionPolicies {
+		if policy.RedactSecrets != nil && !*policy.RedactSecrets {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the policy applies to the secret at secretPath in env
+func (p *RedactionPolicy) Matches(env, secretPath string) bool {
+	if len(p.Environments) > 0 {
+		found := false
+		for _, name := range p.Environments {
+			if name == env {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(p.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range p.Paths {
+		if MatchPath(pattern, secretPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks that the policy's environments exist and its globs and key rules parse
+func (p *RedactionPolicy) validate(c *Configs) error {
+	if _, err := ParseKeyRules(p.RedactedKeys); err != nil {
+		return err
+	}
+
+	for _, name := range p.Environments {
+		if _, ok := c.Environments[name]; !ok {
+			return fmt.Errorf("environment %s not found in config", name)
+		}
+	}
+
+	for _, pattern := range p.Paths {
+		for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid path glob %q: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// MatchPath reports whether a secret path matches a glob. Globs are matched
+// segment by segment with path.Match, so "*" stays within one segment, and a
+// "**" segment matches any number of segments, including none.
+func MatchPath(pattern, secretPath string) bool {
+	return matchSegments(
+		strings.Split(strings.Trim(pattern, "/"), "/"),
+		strings.Split(strings.Trim(secretPath, "/"), "/"),
+	)
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+
+	return len(segments) == 0
+}
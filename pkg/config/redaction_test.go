@@ -0,0 +1,149 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "app/prod/db", path: "app/prod/db", want: true},
+		{pattern: "/app/prod/db/", path: "app/prod/db", want: true},
+		{pattern: "app/*/db", path: "app/prod/db", want: true},
+		{pattern: "app/*", path: "app/prod/db", want: false},
+		{pattern: "*/prod/*", path: "app/prod/db", want: true},
+		{pattern: "app/**", path: "app", want: true},
+		{pattern: "app/**", path: "app/prod/db", want: true},
+		{pattern: "**/db", path: "db", want: true},
+		{pattern: "**/db", path: "app/prod/db", want: true},
+		{pattern: "**/db", path: "app/prod/db2", want: false},
+		{pattern: "app/**/db", path: "app/db", want: true},
+		{pattern: "app/**/db", path: "app/eu/prod/db", want: true},
+		{pattern: "app/**/db", path: "other/eu/prod/db", want: false},
+		{pattern: "**/*secret*", path: "app/prod/secrets", want: true},
+		{pattern: "**/*secret*", path: "app/secrets/db", want: false},
+		{pattern: "**", path: "anything/at/all", want: true},
+		{pattern: "app/prod", path: "app/prod/db", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRedactionForPolicyOrder(t *testing.T) {
+	enabled, disabled := true, false
+	configs := &Configs{
+		Environments: map[string]EnvironmentConfig{"dev": {}, "prod": {}},
+		RedactionPolicies: []RedactionPolicy{
+			{Environments: []string{"prod"}, RedactAll: &enabled},
+			{Paths: []string{"app/*/public"}, RedactAll: &disabled, RedactedKeys: []string{"exact:pin"}},
+			{Environments: []string{"dev"}, RedactSecrets: &disabled},
+		},
+	}
+
+	tests := []struct {
+		env       string
+		path      string
+		policies  []string
+		redactAll bool
+		secrets   bool
+	}{
+		{env: "prod", path: "app/prod/db", policies: []string{"redaction policy 1"}, redactAll: true, secrets: true},
+		{env: "prod", path: "app/prod/public", policies: []string{"redaction policy 1", "redaction policy 2"}, redactAll: false, secrets: true},
+		{env: "dev", path: "app/dev/secrets@3", policies: []string{"redaction policy 3"}, redactAll: false, secrets: false},
+		{env: "staging", path: "app/staging/db", policies: nil, redactAll: false, secrets: true},
+	}
+
+	for _, tt := range tests {
+		redaction := configs.RedactionFor(tt.env, tt.path)
+		if !reflect.DeepEqual(redaction.Policies, tt.policies) {
+			t.Errorf("RedactionFor(%s, %s).Policies = %v, want %v", tt.env, tt.path, redaction.Policies, tt.policies)
+		}
+		if redaction.RedactAll != tt.redactAll || redaction.RedactSecrets != tt.secrets {
+			t.Errorf("RedactionFor(%s, %s) = redact_all %v, redact_secrets %v, want %v, %v",
+				tt.env, tt.path, redaction.RedactAll, redaction.RedactSecrets, tt.redactAll, tt.secrets)
+		}
+	}
+
+	redaction := configs.RedactionFor("prod", "app/prod/public")
+	if redaction.KeysFrom != "redacted_keys of redaction policy 2" || len(redaction.Rules) != 1 {
+		t.Errorf("RedactionFor() keys = %v from %s, want the rules of policy 2", redaction.RedactedKeys, redaction.KeysFrom)
+	}
+}
+
+func TestRedactionDecide(t *testing.T) {
+	rules, err := ParseKeyRules([]string{"*password*", "!exact:password_hint"})
+	if err != nil {
+		t.Fatalf("ParseKeyRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		redaction Redaction
+		key       string
+		flagged   bool
+		want      bool
+	}{
+		{name: "matching rule", redaction: Redaction{RedactSecrets: true, Rules: rules}, key: "db_password", want: true},
+		{name: "no rule", redaction: Redaction{RedactSecrets: true, Rules: rules}, key: "username", want: false},
+		{name: "flagged by the store", redaction: Redaction{RedactSecrets: true, Rules: rules}, key: "username", flagged: true, want: true},
+		{name: "redact_all", redaction: Redaction{RedactSecrets: true, RedactAll: true, Rules: rules}, key: "username", want: true},
+		{name: "allow-list wins over redact_all", redaction: Redaction{RedactSecrets: true, RedactAll: true, Rules: rules}, key: "password_hint", want: false},
+		{name: "allow-list wins over the store", redaction: Redaction{RedactSecrets: true, Rules: rules}, key: "password_hint", flagged: true, want: false},
+		{name: "disabled redaction wins over everything", redaction: Redaction{RedactAll: true, Rules: rules}, key: "db_password", flagged: true, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.redaction.Decide(tt.key, tt.flagged); got.Redacted != tt.want {
+			t.Errorf("%s: Decide(%q) = %v (%s), want %v", tt.name, tt.key, got.Redacted, got.Reason, tt.want)
+		}
+	}
+}
+
+func TestWithSecretPaths(t *testing.T) {
+	disabled := false
+	configs := &Configs{
+		RedactionPolicies: []RedactionPolicy{
+			{Paths: []string{"app/dev/*"}, RedactAll: &disabled},
+		},
+	}
+	scoped := configs.WithSecretPaths("app/prod/secrets", "app/[eu]/Secrets")
+
+	tests := []struct {
+		path      string
+		policies  []string
+		redactAll bool
+	}{
+		{path: "app/prod/secrets", policies: []string{secretPathsPolicy}, redactAll: true},
+		{path: "app/prod/secrets@2", policies: []string{secretPathsPolicy}, redactAll: true},
+		{path: "app/[eu]/Secrets", policies: []string{secretPathsPolicy}, redactAll: true},
+		{path: "app/e/Secrets", policies: nil, redactAll: false},
+		{path: "app/staging/secrets", policies: nil, redactAll: false},
+	}
+
+	for _, tt := range tests {
+		redaction := scoped.RedactionFor("prod", tt.path)
+		if !reflect.DeepEqual(redaction.Policies, tt.policies) || redaction.RedactAll != tt.redactAll {
+			t.Errorf("RedactionFor(%s) = policies %v, redact_all %v, want %v, %v",
+				tt.path, redaction.Policies, redaction.RedactAll, tt.policies, tt.redactAll)
+		}
+	}
+
+	// A policy of the config can still show the values
+	redaction := configs.WithSecretPaths("app/dev/secrets").RedactionFor("dev", "app/dev/secrets")
+	if redaction.RedactAll || redaction.RedactAllFrom != "redaction policy 1" {
+		t.Errorf("RedactionFor() = redact_all %v from %q, want it turned off by policy 1", redaction.RedactAll, redaction.RedactAllFrom)
+	}
+
+	// The original config is left alone
+	if redaction := configs.RedactionFor("prod", "app/prod/secrets"); redaction.RedactAll {
+		t.Errorf("WithSecretPaths() changed the config it was called on")
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Key rule kinds
+const (
+	RuleExact = "exact"
+	RuleGlob  = "glob"
+	RuleRegex = "regex"
+)
+
+// KeyRule is one entry of a key list such as redacted_keys or sensitive_keys.
+// Entries are matched against whole key names, ignoring case:
+//
+//	exact:db_url      exact name
+//	db_url            exact name too, but reported by BareKeyRules
+//	*password*        glob, when the entry has *, ? or [
+//	re:^db_.*_url$    regular expression
+//	exact:, glob:     force the kind of an entry
+//	!monkey_count     allow-list entry, the key is never matched
+type KeyRule struct {
+	Entry   string // as written in the config
+	Kind    string
+	Allow   bool
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// KeyRules is a parsed key list
+type KeyRules []*KeyRule
+
+// ParseKeyRule parses one key list entry
+func ParseKeyRule(entry string) (*KeyRule, error) {
+	rule := &KeyRule{Entry: entry}
+
+	pattern := strings.TrimSpace(entry)
+	if strings.HasPrefix(pattern, "!") {
+		rule.Allow = true
+		pattern = pattern[1:]
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "re:"):
+		rule.Kind, pattern = RuleRegex, strings.TrimPrefix(pattern, "re:")
+	case strings.HasPrefix(pattern, "glob:"):
+		rule.Kind, pattern = RuleGlob, strings.TrimPrefix(pattern, "glob:")
+	case strings.HasPrefix(pattern, "exact:"):
+		rule.Kind, pattern = RuleExact, strings.TrimPrefix(pattern, "exact:")
+	case strings.ContainsAny(pattern, "*?["):
+		rule.Kind = RuleGlob
+	default:
+		rule.Kind = RuleExact
+	}
+
+	if pattern == "" {
+		return nil, fmt.Errorf("empty key rule %q", entry)
+	}
+
+	switch rule.Kind {
+	case RuleRegex:
+		regex, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in key rule %q: %w", entry, err)
+		}
+		rule.regex = regex
+	case RuleGlob:
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob in key rule %q: %w", entry, err)
+		}
+	default:
+		pattern = strings.ToLower(pattern)
+	}
+	rule.pattern = pattern
+
+	return rule, nil
+}
+
+// ParseKeyRules parses a key list, failing on the first invalid entry
+func ParseKeyRules(entries []string) (KeyRules, error) {
+	rules := make(KeyRules, 0, len(entries))
+	for _, entry := range entries {
+		rule, err := ParseKeyRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// IsBare reports whether the entry is a plain word with no kind prefix and no
+// glob characters. Such entries used to match anywhere in a key name and now
+// only match the whole name, so they are flagged until written explicitly.
+func (r *KeyRule) IsBare() bool {
+	entry := strings.TrimPrefix(strings.TrimSpace(r.Entry), "!")
+	return r.Kind == RuleExact && !strings.HasPrefix(entry, "exact:")
+}
+
+// BareKeyRules describes the bare entries of a key list, suggesting the
+// glob that keeps the old substring match and the explicit exact form
+func BareKeyRules(field string, entries []string) []string {
+	var warnings []string
+	for _, entry := range entries {
+		rule, err := ParseKeyRule(entry)
+		if err != nil || !rule.IsBare() {
+			continue
+		}
+
+		prefix := ""
+		if rule.Allow {
+			prefix = "!"
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"%s: %q only matches the whole key name; write %q to match it anywhere in a name, as before, or %q to keep the exact match",
+			field, entry, prefix+"*"+rule.pattern+"*", prefix+"exact:"+rule.pattern))
+	}
+	return warnings
+}
+
+// bareKeyRuleWarnings reports the bare entries of every key list in the config
+func (c *Configs) bareKeyRuleWarnings() []string {
+	warnings := BareKeyRules("redacted_keys", c.RedactedKeys)
+	warnings = append(warnings, BareKeyRules("sensitive_keys", c.SensitiveKeys)...)
+	for i, policy := range c.RedactionPolicies {
+		warnings = append(warnings, BareKeyRules(fmt.Sprintf("redaction policy %d: redacted_keys", i+1), policy.RedactedKeys)...)
+	}
+	return warnings
+}
+
+// Matches reports whether the rule's pattern matches key
+func (r *KeyRule) Matches(key string) bool {
+	switch r.Kind {
+	case RuleRegex:
+		return r.regex.MatchString(key)
+	case RuleGlob:
+		ok, _ := path.Match(r.pattern, strings.ToLower(key))
+		return ok
+	default:
+		return r.pattern == strings.ToLower(key)
+	}
+}
+
+// String describes the rule, e.g. `glob "*password*"`
+func (r *KeyRule) String() string {
+	description := fmt.Sprintf("%s %q", r.Kind, r.pattern)
+	if r.Allow {
+		return "allow-list " + description
+	}
+	return description
+}
+
+// Match returns the rule that decides key: the first allow-list entry that
+// matches it, or else the first other entry, or nil when none does
+func (rules KeyRules) Match(key string) *KeyRule {
+	var matched *KeyRule
+	for _, rule := range rules {
+		if !rule.Matches(key) {
+			continue
+		}
+		if rule.Allow {
+			return rule
+		}
+		if matched == nil {
+			matched = rule
+		}
+	}
+	return matched
+}
+
+// Contains reports whether key matches the list and no allow-list entry
+func (rules KeyRules) Contains(key string) bool {
+	rule := rules.Match(key)
+	return rule != nil && !rule.Allow
+}
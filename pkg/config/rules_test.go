@@ -0,0 +1,173 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseKeyRule(t *testing.T) {
+	tests := []struct {
+		entry string
+		kind  string
+		allow bool
+		bare  bool
+	}{
+		{entry: "db_url", kind: RuleExact, bare: true},
+		{entry: "exact:db_url", kind: RuleExact},
+		{entry: "*password*", kind: RuleGlob},
+		{entry: "glob:db_url", kind: RuleGlob},
+		{entry: "re:^db_.*_url$", kind: RuleRegex},
+		{entry: "!monkey_count", kind: RuleExact, allow: true, bare: true},
+		{entry: "!exact:public_key", kind: RuleExact, allow: true},
+		{entry: "  *token*  ", kind: RuleGlob},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseKeyRule(tt.entry)
+		if err != nil {
+			t.Errorf("ParseKeyRule(%q) error = %v", tt.entry, err)
+			continue
+		}
+		if rule.Kind != tt.kind || rule.Allow != tt.allow || rule.IsBare() != tt.bare {
+			t.Errorf("ParseKeyRule(%q) = kind %s, allow %v, bare %v, want %s, %v, %v",
+				tt.entry, rule.Kind, rule.Allow, rule.IsBare(), tt.kind, tt.allow, tt.bare)
+		}
+	}
+}
+
+func TestParseKeyRuleErrors(t *testing.T) {
+	for _, entry := range []string{"", "!", "re:", "exact:", "re:(", "glob:[a"} {
+		if _, err := ParseKeyRule(entry); err == nil {
+			t.Errorf("ParseKeyRule(%q) should fail", entry)
+		}
+	}
+
+	if _, err := ParseKeyRules([]string{"*password*", "re:["}); err == nil {
+		t.Errorf("ParseKeyRules() should fail on the invalid entry")
+	}
+}
+
+func TestKeyRuleMatches(t *testing.T) {
+	tests := []struct {
+		entry string
+		key   string
+		want  bool
+	}{
+		{entry: "exact:key", key: "key", want: true},
+		{entry: "exact:key", key: "KEY", want: true},
+		{entry: "exact:key", key: "monkey_count", want: false},
+		{entry: "key", key: "api_key", want: false},
+		{entry: "*_key", key: "api_key", want: true},
+		{entry: "*_key", key: "monkey", want: false},
+		{entry: "*password*", key: "DB_Password_Old", want: true},
+		{entry: "db_?", key: "db_1", want: true},
+		{entry: "db_?", key: "db_10", want: false},
+		{entry: "glob:*", key: "anything", want: true},
+		{entry: "re:^db_.*_url$", key: "DB_primary_URL", want: true},
+		{entry: "re:^db_.*_url$", key: "db_url", want: false},
+		{entry: "re:token", key: "github_token_v2", want: true},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseKeyRule(tt.entry)
+		if err != nil {
+			t.Fatalf("ParseKeyRule(%q) error = %v", tt.entry, err)
+		}
+		if got := rule.Matches(tt.key); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.entry, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeyRulesPrecedence(t *testing.T) {
+	rules, err := ParseKeyRules([]string{"*key*", "exact:api_key", "!exact:public_key", "!re:_count$", "*secret*"})
+	if err != nil {
+		t.Fatalf("ParseKeyRules() error = %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		entry    string // entry of the deciding rule, empty for none
+		contains bool
+	}{
+		// The first matching entry decides, not the most specific one
+		{key: "api_key", entry: "*key*", contains: true},
+		// Allow-list entries win even when listed after a match
+		{key: "public_key", entry: "!exact:public_key", contains: false},
+		{key: "monkey_count", entry: "!re:_count$", contains: false},
+		{key: "secret_count", entry: "!re:_count$", contains: false},
+		{key: "client_secret", entry: "*secret*", contains: true},
+		{key: "username", entry: "", contains: false},
+	}
+
+	for _, tt := range tests {
+		rule := rules.Match(tt.key)
+		entry := ""
+		if rule != nil {
+			entry = rule.Entry
+		}
+		if entry != tt.entry {
+			t.Errorf("Match(%q) = %q, want %q", tt.key, entry, tt.entry)
+		}
+		if got := rules.Contains(tt.key); got != tt.contains {
+			t.Errorf("Contains(%q) = %v, want %v", tt.key, got, tt.contains)
+		}
+	}
+}
+
+func TestDefaultRedactedKeys(t *testing.T) {
+	rules, err := ParseKeyRules(DefaultRedactedKeys)
+	if err != nil {
+		t.Fatalf("ParseKeyRules(DefaultRedactedKeys) error = %v", err)
+	}
+
+	for _, key := range []string{"password", "db_passwd", "client_secret", "github_token", "key", "api_key", "ssh-key", "auth", "basic_auth", "auth_header", "tls_cert", "ca_certificate"} {
+		if !rules.Contains(key) {
+			t.Errorf("default rules should redact %q", key)
+		}
+	}
+	for _, key := range []string{"monkey_count", "keyboard_layout", "author", "passenger_count", "certain", "username"} {
+		if rules.Contains(key) {
+			t.Errorf("default rules should not redact %q", key)
+		}
+	}
+
+	if warnings := BareKeyRules("redacted_keys", DefaultRedactedKeys); len(warnings) != 0 {
+		t.Errorf("default rules should have no bare entries, got %v", warnings)
+	}
+}
+
+func TestBareKeyRules(t *testing.T) {
+	warnings := BareKeyRules("redacted_keys", []string{"password", "exact:token", "*secret*", "!monkey", "re:(", "re:^pin$"})
+	if len(warnings) != 2 {
+		t.Fatalf("BareKeyRules() = %v, want two warnings", warnings)
+	}
+
+	for i, want := range []string{
+		`redacted_keys: "password" only matches the whole key name; write "*password*" to match it anywhere in a name, as before, or "exact:password" to keep the exact match`,
+		`redacted_keys: "!monkey" only matches the whole key name; write "!*monkey*" to match it anywhere in a name, as before, or "!exact:monkey" to keep the exact match`,
+	} {
+		if warnings[i] != want {
+			t.Errorf("warning %d = %s, want %s", i, warnings[i], want)
+		}
+	}
+}
+
+func TestBareKeyRuleWarnings(t *testing.T) {
+	configs := &Configs{
+		RedactedKeys:  []string{"token"},
+		SensitiveKeys: []string{"*token*"},
+		RedactionPolicies: []RedactionPolicy{
+			{RedactedKeys: []string{"exact:pin"}},
+			{RedactedKeys: []string{"pin"}},
+		},
+	}
+
+	warnings := configs.bareKeyRuleWarnings()
+	if len(warnings) != 2 {
+		t.Fatalf("bareKeyRuleWarnings() = %v, want two warnings", warnings)
+	}
+	if !strings.HasPrefix(warnings[0], "redacted_keys: ") || !strings.HasPrefix(warnings[1], "redaction policy 2: redacted_keys: ") {
+		t.Errorf("bareKeyRuleWarnings() = %v, want them named after their field", warnings)
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// tokenSources returns the names of the Vault token sources set in the config
+func (e *EnvironmentConfig) tokenSources() []string {
+	var sources []string
+	if e.TokenEnv != "" {
+		sources = append(sources, "token_env")
+	}
+	if e.TokenFile != "" {
+		sources = append(sources, "token_file")
+	}
+	if e.TokenHelper != "" {
+		sources = append(sources, "token_helper")
+	}
+	if e.TokenCommand != "" {
+		sources = append(sources, "token_command")
+	}
+	return sources
+}
+
+// validateTokenSource checks that exactly one Vault token source is set
+func (e *EnvironmentConfig) validateTokenSource() error {
+	sources := e.tokenSources()
+	switch len(sources) {
+	case 0:
+		return fmt.Errorf("token_env, token_file, token_helper or token_command not specified")
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("only one token source may be set, found %s", strings.Join(sources, " and "))
+	}
+}
+
+// GetVaultToken retrieves the Vault token from the configured source: an
+// environment variable, a file, a Vault token helper or a shell command
+func (e *EnvironmentConfig) GetVaultToken() (string, error) {
+	if err := e.validateTokenSource(); err != nil {
+		return "", fmt.Errorf("%w in the environment config", err)
+	}
+
+	switch {
+	case e.TokenFile != "":
+		path, err := ExpandHome(e.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return readFile(path)
+	case e.TokenHelper != "":
+		helper, err := ExpandHome(e.TokenHelper)
+		if err != nil {
+			return "", err
+		}
+		return e.runTokenCommand(exec.Command(helper, "get"), "token helper "+e.TokenHelper)
+	case e.TokenCommand != "":
+		return e.runTokenCommand(exec.Command("sh", "-c", e.TokenCommand), "token_command")
+	}
+
+	return readEnv(e.TokenEnv)
+}
+
+// runTokenCommand runs a command that prints the token on stdout. VAULT_ADDR
+// is set to the environment's URL, so helpers that keep one token per server
+// return the right one. Stdin and stderr are passed through for prompts.
+func (e *EnvironmentConfig) runTokenCommand(cmd *exec.Cmd, name string) (string, error) {
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if e.URL != "" {
+		cmd.Env = append(cmd.Env, "VAULT_ADDR="+e.URL)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w", name, err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("%s printed no token", name)
+	}
+	return token, nil
+}
+
+// ExpandHome replaces a leading "~/" in path with the user's home directory
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory for %s: %w", path, err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
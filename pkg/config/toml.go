@@ -0,0 +1,39 @@
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+// parseTOML parses a TOML document into nested maps. Arrays of tables become
+// plain lists so the result has the same shape as a parsed JSON or YAML file.
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return nil, err
+	}
+	return normalizeTOML(raw).(map[string]interface{}), nil
+}
+
+// normalizeTOML converts the []map[string]interface{} the decoder uses for
+// arrays of tables into []interface{}, at any depth
+func normalizeTOML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeTOML(item)
+		}
+		return v
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalizeTOML(item)
+		}
+		return list
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeTOML(item)
+		}
+		return v
+	}
+	return value
+}
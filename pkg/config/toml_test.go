@@ -0,0 +1,135 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "decimal integer",
+			input: "timeout = 10",
+			want:  map[string]interface{}{"timeout": int64(10)},
+		},
+		{
+			name:  "underscores in integers",
+			input: "size = 1_000",
+			want:  map[string]interface{}{"size": int64(1000)},
+		},
+		{
+			name:  "prefixed integers",
+			input: "hex = 0x1F\noctal = 0o17\nbinary = 0b101",
+			want:  map[string]interface{}{"hex": int64(31), "octal": int64(15), "binary": int64(5)},
+		},
+		{
+			name:  "float",
+			input: "ratio = 0.5",
+			want:  map[string]interface{}{"ratio": 0.5},
+		},
+		{
+			name:  "multi-line basic string",
+			input: "ca = \"\"\"\nline one\nline two\"\"\"",
+			want:  map[string]interface{}{"ca": "line one\nline two"},
+		},
+		{
+			name:  "multi-line literal string",
+			input: "pattern = '''\nre:^db_\\w+$'''",
+			want:  map[string]interface{}{"pattern": `re:^db_\w+$`},
+		},
+		{
+			name:  "dotted keys and tables",
+			input: "[environments.dev]\nurl = \"https://vault-dev\"\nauth.method = \"approle\"",
+			want: map[string]interface{}{
+				"environments": map[string]interface{}{
+					"dev": map[string]interface{}{
+						"url":  "https://vault-dev",
+						"auth": map[string]interface{}{"method": "approle"},
+					},
+				},
+			},
+		},
+		{
+			name: "array of tables",
+			input: `
+[[redaction_policies]]
+environments = ["dev"]
+redact_secrets = false
+
+[[redaction_policies]]
+paths = ["*/prod/secrets/**"]
+redact_all = true
+`,
+			want: map[string]interface{}{
+				"redaction_policies": []interface{}{
+					map[string]interface{}{"environments": []interface{}{"dev"}, "redact_secrets": false},
+					map[string]interface{}{"paths": []interface{}{"*/prod/secrets/**"}, "redact_all": true},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("parseTOML() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "leading zero", input: "timeout = 010"},
+		{name: "duplicate key", input: "a = 1\na = 2"},
+		{name: "unterminated string", input: "a = \"open"},
+		{name: "missing value", input: "a ="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parseTOML([]byte(tt.input)); err == nil {
+				t.Errorf("parseTOML() = %#v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestDecodeTOMLConfigs(t *testing.T) {
+	raw, err := parseTOML([]byte(`
+[environments.dev]
+url = "https://vault-dev.example.com"
+token_env = "VAULT_TOKEN"
+
+[[redaction_policies]]
+environments = ["dev"]
+redacted_keys = ["*password*"]
+`))
+	if err != nil {
+		t.Fatalf("parseTOML() error = %v", err)
+	}
+
+	configs, err := decodeConfigs(raw, "test.toml")
+	if err != nil {
+		t.Fatalf("decodeConfigs() error = %v", err)
+	}
+
+	if len(configs.RedactionPolicies) != 1 {
+		t.Fatalf("got %d redaction policies, want 1", len(configs.RedactionPolicies))
+	}
+	policy := configs.RedactionPolicies[0]
+	if !reflect.DeepEqual(policy.Environments, []string{"dev"}) || !reflect.DeepEqual(policy.RedactedKeys, []string{"*password*"}) {
+		t.Errorf("got policy %+v", policy)
+	}
+}
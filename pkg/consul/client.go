@@ -0,0 +1,431 @@
+package consul
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// maxTxnOps is the number of operations Consul accepts in one transaction
+const maxTxnOps = 64
+
+// Layouts of a secret in the KV store
+const (
+	layoutKey  = "key"  // one key holding a JSON object or a plain value
+	layoutFlat = "flat" // one key per secret key directly under the path
+)
+
+// Client handles interactions with the Consul KV HTTP API.
+// A path is either a single key holding a JSON document, or a prefix whose
+// direct child keys are the keys of the secret.
+type Client struct {
+	address    string
+	token      string
+	datacenter string
+	httpClient *http.Client
+}
+
+// kvPair is a key as returned by the KV API
+type kvPair struct {
+	Key         string `json:"Key"`
+	Value       string `json:"Value"` // base64-encoded
+	Flags       uint64 `json:"Flags"`
+	CreateIndex uint64 `json:"CreateIndex"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+// txnOp is one operation of a KV transaction
+type txnOp struct {
+	KV txnKVOp `json:"KV"`
+}
+
+// txnKVOp is a set or delete of a single key
+type txnKVOp struct {
+	Verb  string `json:"Verb"`
+	Key   string `json:"Key"`
+	Value string `json:"Value,omitempty"`
+}
+
+// NewClient creates a Consul client. The ACL token is optional.
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	if envConfig.URL == "" {
+		return nil, fmt.Errorf("url is required for Consul")
+	}
+
+	if _, err := url.Parse(envConfig.URL); err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", envConfig.URL, err)
+	}
+
+	var token string
+	if envConfig.TokenEnv != "" {
+		token = strings.TrimSpace(os.Getenv(envConfig.TokenEnv))
+		if token == "" {
+			return nil, fmt.Errorf("environment variable %s not set or empty", envConfig.TokenEnv)
+		}
+	}
+
+	return &Client{
+		address:    strings.TrimRight(envConfig.URL, "/"),
+		token:      token,
+		datacenter: envConfig.Datacenter,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreConsul
+}
+
+// do sends a request and decodes the JSON response into out.
+// A 404 response is reported as store.ErrSecretNotFound.
+func (c *Client) do(method, endpoint string, query url.Values, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.datacenter != "" {
+		query.Set("dc", c.datacenter)
+	}
+
+	requestURL := c.address + endpoint
+	if encoded := query.Encode(); encoded != "" {
+		requestURL += "?" + encoded
+	}
+
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to Consul failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return store.ErrSecretNotFound
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("consul returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode Consul response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// kvEndpoint builds the KV API endpoint of a key, escaping each segment
+func kvEndpoint(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/v1/kv/" + strings.Join(segments, "/")
+}
+
+// getKey reads a single key, returning nil when it doesn't exist
+func (c *Client) getKey(key string) (*kvPair, error) {
+	var pairs []kvPair
+	if err := c.do(http.MethodGet, kvEndpoint(key), nil, nil, &pairs); err != nil {
+		if store.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	return &pairs[0], nil
+}
+
+// getChildren reads the keys directly under prefix, keyed by their name relative to it
+func (c *Client) getChildren(prefix string) (map[string]kvPair, error) {
+	var pairs []kvPair
+	err := c.do(http.MethodGet, kvEndpoint(prefix+"/"), url.Values{"recurse": {""}}, nil, &pairs)
+	if err != nil && !store.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get keys under %s: %w", prefix, err)
+	}
+
+	children := make(map[string]kvPair)
+	for _, pair := range pairs {
+		name := strings.TrimPrefix(pair.Key, prefix+"/")
+		// Skip the folder marker and anything in deeper folders
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		children[name] = pair
+	}
+
+	return children, nil
+}
+
+// decodeValue decodes the base64 value of a key
+func decodeValue(pair kvPair) (string, error) {
+	value, err := base64.StdEncoding.DecodeString(pair.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode value of %s: %w", pair.Key, err)
+	}
+	return string(value), nil
+}
+
+// txn applies the operations in transactions of at most maxTxnOps
+func (c *Client) txn(ops []txnOp) error {
+	for start := 0; start < len(ops); start += maxTxnOps {
+		end := start + maxTxnOps
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		if err := c.do(http.MethodPut, "/v1/txn", nil, ops[start:end], nil); err != nil {
+			return fmt.Errorf("failed to apply KV transaction: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// setOp builds a transaction operation storing value at key
+func setOp(key, value string) txnOp {
+	return txnOp{KV: txnKVOp{Verb: "set", Key: key, Value: base64.StdEncoding.EncodeToString([]byte(value))}}
+}
+
+// deleteOp builds a transaction operation deleting key
+func deleteOp(key string) txnOp {
+	return txnOp{KV: txnKVOp{Verb: "delete", Key: key}}
+}
+
+// GetSecret reads the key at path as a JSON document or plain value.
+// If there is no such key, the keys directly under path are read instead.
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	key := strings.Trim(path, "/")
+
+	pair, err := c.getKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if pair != nil {
+		value, err := decodeValue(*pair)
+		if err != nil {
+			return nil, err
+		}
+		return store.ParseSecretString(value), nil
+	}
+
+	children, err := c.getChildren(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(children) == 0 {
+		return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+	}
+
+	secret := store.NewSecret(nil)
+	for name, child := range children {
+		value, err := decodeValue(child)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[name] = value
+	}
+
+	return secret, nil
+}
+
+// WriteSecret writes the secret in the layout already used at path.
+// New secrets are written one key per secret key, the layout most Consul
+// consumers expect, unless the secret is a single plain value.
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	key := strings.Trim(path, "/")
+
+	pair, err := c.getKey(key)
+	if err != nil {
+		return err
+	}
+
+	if pair != nil || !secret.IsJSON {
+		value, err := store.MarshalSecretString(secret)
+		if err != nil {
+			return err
+		}
+		return c.txn([]txnOp{setOp(key, value)})
+	}
+
+	children, err := c.getChildren(key)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(secret.Data))
+	for name := range secret.Data {
+		if strings.Contains(name, "/") {
+			return fmt.Errorf("key %q can't be stored under a Consul prefix because it contains '/'", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ops []txnOp
+	for _, name := range names {
+		ops = append(ops, setOp(key+"/"+name, store.StringValue(secret.Data[name])))
+	}
+	for name := range children {
+		if _, ok := secret.Data[name]; !ok {
+			ops = append(ops, deleteOp(key+"/"+name))
+		}
+	}
+
+	return c.txn(ops)
+}
+
+// ListSecrets lists the keys and "/"-suffixed folders directly under prefix
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	base := strings.Trim(prefix, "/")
+	if base != "" {
+		base += "/"
+	}
+
+	var keys []string
+	err := c.do(http.MethodGet, kvEndpoint(base), url.Values{"keys": {""}, "separator": {"/"}}, nil, &keys)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key != base {
+			names = append(names, key)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeleteSecret deletes the key at path, or the keys directly under it.
+// Deeper folders are left alone.
+func (c *Client) DeleteSecret(path string) error {
+	key := strings.Trim(path, "/")
+
+	pair, err := c.getKey(key)
+	if err != nil {
+		return err
+	}
+
+	if pair != nil {
+		return c.txn([]txnOp{deleteOp(key)})
+	}
+
+	children, err := c.getChildren(key)
+	if err != nil {
+		return err
+	}
+
+	if len(children) == 0 {
+		return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+	}
+
+	var ops []txnOp
+	for name := range children {
+		ops = append(ops, deleteOp(key+"/"+name))
+	}
+
+	return c.txn(ops)
+}
+
+// SecretExists checks whether a key exists at or directly under path
+func (c *Client) SecretExists(path string) (bool, error) {
+	_, err := c.GetMetadata(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetMetadata returns the modify index of the secret as its version.
+// Consul KV keeps no timestamps.
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	key := strings.Trim(path, "/")
+
+	pair, err := c.getKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if pair != nil {
+		return &store.Metadata{
+			Path:    path,
+			Version: strconv.FormatUint(pair.ModifyIndex, 10),
+			Labels: map[string]string{
+				"layout": layoutKey,
+				"flags":  strconv.FormatUint(pair.Flags, 10),
+			},
+		}, nil
+	}
+
+	children, err := c.getChildren(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(children) == 0 {
+		return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+	}
+
+	var index uint64
+	for _, child := range children {
+		if child.ModifyIndex > index {
+			index = child.ModifyIndex
+		}
+	}
+
+	return &store.Metadata{
+		Path:    path,
+		Version: strconv.FormatUint(index, 10),
+		Labels:  map[string]string{"layout": layoutFlat},
+	}, nil
+}
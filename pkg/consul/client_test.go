@@ -0,0 +1,375 @@
+package consul
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// fakeConsul serves the parts of the KV and transaction APIs the client uses
+type fakeConsul struct {
+	t      *testing.T
+	server *httptest.Server
+	mu     sync.Mutex
+	kv     map[string]kvPair
+	index  uint64
+	txns   []int // number of operations in each transaction
+}
+
+func newFakeConsul(t *testing.T) *fakeConsul {
+	f := &fakeConsul{t: t, kv: make(map[string]kvPair)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeConsul) client() *Client {
+	return &Client{
+		address:    f.server.URL,
+		token:      "token",
+		datacenter: "dc1",
+		httpClient: f.server.Client(),
+	}
+}
+
+// set stores a key as if it had been written by another client
+func (f *fakeConsul) set(key, value string) {
+	f.index++
+	f.kv[key] = kvPair{
+		Key:         key,
+		Value:       base64.StdEncoding.EncodeToString([]byte(value)),
+		CreateIndex: f.index,
+		ModifyIndex: f.index,
+	}
+}
+
+// value returns the decoded value of a key
+func (f *fakeConsul) value(key string) (string, bool) {
+	pair, ok := f.kv[key]
+	if !ok {
+		return "", false
+	}
+	value, err := base64.StdEncoding.DecodeString(pair.Value)
+	if err != nil {
+		f.t.Fatalf("value of %s is not base64: %v", key, err)
+	}
+	return string(value), true
+}
+
+func (f *fakeConsul) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("X-Consul-Token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.URL.Query().Get("dc") != "dc1" {
+		f.t.Errorf("request %s %s without the datacenter", r.Method, r.URL)
+	}
+
+	if r.URL.Path == "/v1/txn" && r.Method == http.MethodPut {
+		f.txn(w, r)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	query := r.URL.Query()
+
+	var result interface{}
+	switch {
+	case query.Has("keys"):
+		seen := make(map[string]bool)
+		var keys []string
+		for name := range f.kv {
+			if !strings.HasPrefix(name, key) {
+				continue
+			}
+			if idx := strings.Index(name[len(key):], query.Get("separator")); idx >= 0 {
+				name = name[:len(key)+idx+1]
+			}
+			if !seen[name] {
+				seen[name] = true
+				keys = append(keys, name)
+			}
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		result = keys
+	case query.Has("recurse"):
+		var pairs []kvPair
+		for name, pair := range f.kv {
+			if strings.HasPrefix(name, key) {
+				pairs = append(pairs, pair)
+			}
+		}
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		result = pairs
+	default:
+		pair, ok := f.kv[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		result = []kvPair{pair}
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		f.t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+// txn applies a transaction, rejecting it like Consul when it is too large
+func (f *fakeConsul) txn(w http.ResponseWriter, r *http.Request) {
+	var ops []txnOp
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		f.t.Errorf("failed to decode transaction: %v", err)
+	}
+
+	if len(ops) > maxTxnOps {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "Transaction contains too many operations (%d > %d)", len(ops), maxTxnOps)
+		return
+	}
+	f.txns = append(f.txns, len(ops))
+
+	for _, op := range ops {
+		switch op.KV.Verb {
+		case "set":
+			f.index++
+			pair := f.kv[op.KV.Key]
+			if pair.CreateIndex == 0 {
+				pair.CreateIndex = f.index
+			}
+			pair.Key = op.KV.Key
+			pair.Value = op.KV.Value
+			pair.ModifyIndex = f.index
+			f.kv[op.KV.Key] = pair
+		case "delete":
+			delete(f.kv, op.KV.Key)
+		default:
+			f.t.Errorf("unexpected transaction verb %q", op.KV.Verb)
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"Results": []interface{}{}})
+}
+
+func TestWriteNewSecretAsFlatKeys(t *testing.T) {
+	fake := newFakeConsul(t)
+	client := fake.client()
+
+	secret := store.NewSecret(map[string]interface{}{
+		"username": "app",
+		"password": "hunter2",
+		"options":  map[string]interface{}{"pool": float64(5)},
+	})
+	if err := client.WriteSecret("app/dev/db", secret); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	want := map[string]string{
+		"app/dev/db/username": "app",
+		"app/dev/db/password": "hunter2",
+		"app/dev/db/options":  `{"pool":5}`,
+	}
+	for key, value := range want {
+		if got, ok := fake.value(key); !ok || got != value {
+			t.Errorf("key %s = %q, %v, want %q", key, got, ok, value)
+		}
+	}
+	if !reflect.DeepEqual(fake.txns, []int{3}) {
+		t.Errorf("transactions = %v, want a single one with 3 operations", fake.txns)
+	}
+
+	got, err := client.GetSecret("app/dev/db")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	wantData := map[string]interface{}{"username": "app", "password": "hunter2", "options": `{"pool":5}`}
+	if !got.IsJSON || !reflect.DeepEqual(got.Data, wantData) {
+		t.Errorf("GetSecret() = %v, want %v", got.Data, wantData)
+	}
+
+	metadata, err := client.GetMetadata("app/dev/db")
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if metadata.Version != "3" || metadata.Labels["layout"] != layoutFlat {
+		t.Errorf("GetMetadata() = version %q, labels %v", metadata.Version, metadata.Labels)
+	}
+}
+
+func TestWriteFlatSecretRemovesGoneKeys(t *testing.T) {
+	fake := newFakeConsul(t)
+	client := fake.client()
+
+	fake.set("app/db/username", "app")
+	fake.set("app/db/old", "stale")
+	fake.set("app/db/nested/key", "kept")
+
+	secret := store.NewSecret(map[string]interface{}{"username": "app2"})
+	if err := client.WriteSecret("app/db", secret); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	if got, _ := fake.value("app/db/username"); got != "app2" {
+		t.Errorf("username = %q, want app2", got)
+	}
+	if _, ok := fake.kv["app/db/old"]; ok {
+		t.Errorf("key removed from the secret is still stored")
+	}
+	if _, ok := fake.kv["app/db/nested/key"]; !ok {
+		t.Errorf("key in a deeper folder was deleted")
+	}
+}
+
+func TestWriteBatchesTransactions(t *testing.T) {
+	fake := newFakeConsul(t)
+	client := fake.client()
+
+	data := make(map[string]interface{})
+	for i := 0; i < 2*maxTxnOps+10; i++ {
+		data[fmt.Sprintf("key%03d", i)] = fmt.Sprintf("value%d", i)
+	}
+	if err := client.WriteSecret("app/big", store.NewSecret(data)); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	if want := []int{maxTxnOps, maxTxnOps, 10}; !reflect.DeepEqual(fake.txns, want) {
+		t.Errorf("transactions = %v, want %v", fake.txns, want)
+	}
+	if len(fake.kv) != len(data) {
+		t.Errorf("stored %d keys, want %d", len(fake.kv), len(data))
+	}
+
+	fake.txns = nil
+	if err := client.DeleteSecret("app/big"); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if want := []int{maxTxnOps, maxTxnOps, 10}; !reflect.DeepEqual(fake.txns, want) {
+		t.Errorf("delete transactions = %v, want %v", fake.txns, want)
+	}
+	if len(fake.kv) != 0 {
+		t.Errorf("%d keys left after delete", len(fake.kv))
+	}
+}
+
+func TestSingleKeyLayout(t *testing.T) {
+	fake := newFakeConsul(t)
+	client := fake.client()
+
+	fake.set("app/config", `{"debug":"true"}`)
+
+	got, err := client.GetSecret("app/config")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if !got.IsJSON || got.Data["debug"] != "true" {
+		t.Errorf("GetSecret() = %v, want the JSON document", got.Data)
+	}
+
+	// An existing JSON document stays a single key
+	got.Data["debug"] = "false"
+	if err := client.WriteSecret("app/config", got); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if value, _ := fake.value("app/config"); value != `{"debug":"false"}` {
+		t.Errorf("app/config = %q, want the updated JSON document", value)
+	}
+	if _, ok := fake.kv["app/config/debug"]; ok {
+		t.Errorf("single key secret was rewritten as flat keys")
+	}
+
+	metadata, err := client.GetMetadata("app/config")
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if metadata.Labels["layout"] != layoutKey {
+		t.Errorf("layout = %q, want %q", metadata.Labels["layout"], layoutKey)
+	}
+
+	// A plain value is written to a single key too
+	plain := store.NewSecret(map[string]interface{}{"value": "s3cr3t"})
+	plain.IsJSON = false
+	if err := client.WriteSecret("app/api-key", plain); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if value, _ := fake.value("app/api-key"); value != "s3cr3t" {
+		t.Errorf("app/api-key = %q, want the plain value", value)
+	}
+}
+
+func TestWriteRejectsSlashInKey(t *testing.T) {
+	client := newFakeConsul(t).client()
+
+	secret := store.NewSecret(map[string]interface{}{"a/b": "value"})
+	if err := client.WriteSecret("app/db", secret); err == nil {
+		t.Errorf("WriteSecret() should refuse a key containing '/'")
+	}
+}
+
+func TestGetSecretNotFound(t *testing.T) {
+	fake := newFakeConsul(t)
+	client := fake.client()
+
+	fake.set("app/db/nested/key", "value")
+
+	// Keys in deeper folders don't make a secret
+	if _, err := client.GetSecret("app/db"); !store.IsNotFound(err) {
+		t.Errorf("GetSecret() error = %v, want not found", err)
+	}
+
+	exists, err := client.SecretExists("app/missing")
+	if err != nil || exists {
+		t.Errorf("SecretExists() = %v, %v, want false", exists, err)
+	}
+
+	if err := client.DeleteSecret("app/missing"); !store.IsNotFound(err) {
+		t.Errorf("DeleteSecret() error = %v, want not found", err)
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	fake := newFakeConsul(t)
+	client := fake.client()
+
+	for _, key := range []string{"app/dev/db/username", "app/dev/api", "app/prod/db/password", "app/shared", "other"} {
+		fake.set(key, "value")
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"app/", "other"}},
+		{prefix: "app", want: []string{"app/dev/", "app/prod/", "app/shared"}},
+		{prefix: "/app/dev/", want: []string{"app/dev/api", "app/dev/db/"}},
+		{prefix: "missing", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		got, err := client.ListSecrets(tt.prefix)
+		if err != nil {
+			t.Fatalf("ListSecrets(%q) error = %v", tt.prefix, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListSecrets(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package filestore
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// fileExtension marks encrypted secret files in the store directory
+	fileExtension = ".enc"
+
+	// algorithm is recorded in every file so the format can evolve
+	algorithm = "AES-256-GCM"
+
+	// Supported formats of the encrypted documents
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Client keeps secrets as encrypted documents in a local directory.
+// The path "app/dev/config" is stored in "<directory>/app/dev/config.enc".
+type Client struct {
+	directory string
+	format    string
+	aead      cipher.AEAD
+}
+
+// envelope is the on-disk form of a secret file
+type envelope struct {
+	Algorithm  string `json:"algorithm"`
+	Format     string `json:"format"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// document is the decrypted content of a secret file
+type document struct {
+	Data        map[string]interface{} `json:"data" yaml:"data"`
+	Opaque      bool                   `json:"opaque,omitempty" yaml:"opaque,omitempty"`
+	Binary      bool                   `json:"binary,omitempty" yaml:"binary,omitempty"`
+	Sensitive   []string               `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+	Revision    int                    `json:"revision" yaml:"revision"`
+	CreatedTime time.Time              `json:"created_time" yaml:"created_time"`
+	UpdatedTime time.Time              `json:"updated_time" yaml:"updated_time"`
+}
+
+// NewClient opens the encrypted file store of the environment
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	if envConfig.Directory == "" {
+		return nil, fmt.Errorf("directory is required for the file store")
+	}
+
+	format := envConfig.Format
+	if format == "" {
+		format = FormatJSON
+	}
+	if format != FormatJSON && format != FormatYAML {
+		return nil, fmt.Errorf("unsupported file store format %s, must be %s or %s", format, FormatJSON, FormatYAML)
+	}
+
+	key, err := loadKey(envConfig.KeyEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load file store key: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		directory: envConfig.Directory,
+		format:    format,
+		aead:      aead,
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreFile
+}
+
+// Provision creates the store directory if it is missing
+func (c *Client) Provision() error {
+	if err := os.MkdirAll(c.directory, 0700); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return nil
+}
+
+// cleanPath validates a store path and returns it without surrounding slashes
+func cleanPath(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("empty path")
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid path %s", path)
+		}
+	}
+
+	return path, nil
+}
+
+// filePath returns the file holding the secret at path
+func (c *Client) filePath(path string) (string, string, error) {
+	clean, err := cleanPath(path)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(c.directory, filepath.FromSlash(clean)) + fileExtension, clean, nil
+}
+
+// readDocument loads and decrypts the document at path
+func (c *Client) readDocument(path string) (*document, error) {
+	file, clean, err := c.filePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse secret file %s: %w", file, err)
+	}
+
+	if env.Algorithm != algorithm {
+		return nil, fmt.Errorf("unsupported encryption algorithm %q in %s", env.Algorithm, file)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode nonce in %s: %w", file, err)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext in %s: %w", file, err)
+	}
+
+	plaintext, err := open(c.aead, nonce, ciphertext, []byte(clean))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var doc document
+	switch env.Format {
+	case FormatYAML:
+		err = yaml.Unmarshal(plaintext, &doc)
+	default:
+		err = json.Unmarshal(plaintext, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted %s: %w", file, err)
+	}
+
+	if doc.Data == nil {
+		doc.Data = make(map[string]interface{})
+	}
+
+	return &doc, nil
+}
+
+// writeDocument encrypts the document and atomically replaces the file at path
+func (c *Client) writeDocument(path string, doc *document) error {
+	file, clean, err := c.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	var plaintext []byte
+	if c.format == FormatYAML {
+		plaintext, err = yaml.Marshal(doc)
+	} else {
+		plaintext, err = json.Marshal(doc)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret document: %w", err)
+	}
+
+	nonce, ciphertext, err := seal(c.aead, plaintext, []byte(clean))
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(envelope{
+		Algorithm:  algorithm,
+		Format:     c.format,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("failed to create secret directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write never leaves a truncated secret
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write secret file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write secret file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to replace secret file: %w", err)
+	}
+
+	return nil
+}
+
+// GetSecret decrypts the secret at path
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	doc, err := c.readDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := store.NewSecret(doc.Data)
+	secret.IsJSON = !doc.Opaque
+	secret.Binary = doc.Binary
+	for _, key := range doc.Sensitive {
+		secret.Sensitive[key] = true
+	}
+
+	return secret, nil
+}
+
+// SupportsBinary reports that binary secrets are kept as such in the encrypted files
+func (c *Client) SupportsBinary() bool {
+	return true
+}
+
+// WriteSecret encrypts the secret to its file, keeping the sensitive flags of its keys
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	now := time.Now().UTC()
+	doc := &document{
+		Data:        secret.Data,
+		Opaque:      !secret.IsJSON,
+		Binary:      secret.Binary,
+		Revision:    1,
+		CreatedTime: now,
+		UpdatedTime: now,
+	}
+
+	existing, err := c.readDocument(path)
+	if err != nil && !store.IsNotFound(err) {
+		return err
+	}
+	if existing != nil {
+		doc.Revision = existing.Revision + 1
+		doc.CreatedTime = existing.CreatedTime
+	}
+
+	for key := range secret.Data {
+		if secret.IsSensitive(key) {
+			doc.Sensitive = append(doc.Sensitive, key)
+		}
+	}
+	sort.Strings(doc.Sensitive)
+
+	return c.writeDocument(path, doc)
+}
+
+// ListSecrets lists the secret files and subdirectories directly under prefix
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+
+	dir := c.directory
+	if prefix != "" {
+		clean, err := cleanPath(prefix)
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(c.directory, filepath.FromSlash(clean))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	base := prefix
+	if base != "" {
+		base += "/"
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir():
+			names = append(names, base+entry.Name()+"/")
+		case strings.HasSuffix(entry.Name(), fileExtension):
+			names = append(names, base+strings.TrimSuffix(entry.Name(), fileExtension))
+		}
+	}
+
+	return names, nil
+}
+
+// DeleteSecret removes the secret file
+func (c *Client) DeleteSecret(path string) error {
+	file, _, err := c.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+
+	return nil
+}
+
+// SecretExists checks whether a secret file exists at path
+func (c *Client) SecretExists(path string) (bool, error) {
+	file, _, err := c.filePath(path)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check secret file: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetMetadata returns the revision and timestamps kept in the secret file
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	doc, err := c.readDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.Metadata{
+		Path:        path,
+		Version:     strconv.Itoa(doc.Revision),
+		CreatedTime: doc.CreatedTime,
+		UpdatedTime: doc.UpdatedTime,
+		Labels:      map[string]string{"format": c.format},
+	}, nil
+}
@@ -0,0 +1,117 @@
+package filestore
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// newTestClient opens a file store in dir with a key made of fill bytes
+func newTestClient(t *testing.T, dir, format string, fill byte) *Client {
+	t.Helper()
+	t.Setenv("VP_TEST_FILE_KEY", base64.StdEncoding.EncodeToString(testKey(fill)))
+	client, err := NewClient(&config.EnvironmentConfig{Directory: dir, KeyEnv: "VP_TEST_FILE_KEY", Format: format})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		client := newTestClient(t, t.TempDir(), format, 1)
+
+		secret := store.NewSecret(map[string]interface{}{"username": "app", "password": "hunter2"})
+		secret.Sensitive["password"] = true
+		if err := client.WriteSecret("app/dev/db", secret); err != nil {
+			t.Fatalf("%s: WriteSecret() error = %v", format, err)
+		}
+
+		raw, err := os.ReadFile(filepath.Join(client.directory, "app", "dev", "db.enc"))
+		if err != nil {
+			t.Fatalf("%s: secret file not written: %v", format, err)
+		}
+		if strings.Contains(string(raw), "hunter2") {
+			t.Errorf("%s: secret file holds the plaintext password", format)
+		}
+
+		got, err := client.GetSecret("app/dev/db")
+		if err != nil {
+			t.Fatalf("%s: GetSecret() error = %v", format, err)
+		}
+		if !reflect.DeepEqual(got.Data, secret.Data) || !got.IsSensitive("password") || got.IsSensitive("username") {
+			t.Errorf("%s: GetSecret() = %v, sensitive %v, want %v with password sensitive", format, got.Data, got.Sensitive, secret.Data)
+		}
+
+		binary := store.NewBinarySecret([]byte{0x00, 0xff, 0x10})
+		if err := client.WriteSecret("app/dev/keystore", binary); err != nil {
+			t.Fatalf("%s: WriteSecret() error = %v", format, err)
+		}
+		got, err = client.GetSecret("app/dev/keystore")
+		if err != nil {
+			t.Fatalf("%s: GetSecret() error = %v", format, err)
+		}
+		if content, err := got.BinaryContent(); err != nil || string(content) != "\x00\xff\x10" {
+			t.Errorf("%s: GetSecret() binary = %q, %v, want the written bytes", format, content, err)
+		}
+	}
+}
+
+func TestReadRejectsWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := newTestClient(t, dir, FormatJSON, 1).WriteSecret("app/db", store.NewSecret(map[string]interface{}{"password": "hunter2"})); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	if _, err := newTestClient(t, dir, FormatJSON, 2).GetSecret("app/db"); err == nil || store.IsNotFound(err) {
+		t.Errorf("GetSecret() with another key error = %v, want a decryption error", err)
+	}
+}
+
+func TestReadRejectsSwappedFile(t *testing.T) {
+	client := newTestClient(t, t.TempDir(), FormatJSON, 1)
+
+	for path, password := range map[string]string{"app/dev/db": "dev-pw", "app/prod/db": "prod-pw"} {
+		if err := client.WriteSecret(path, store.NewSecret(map[string]interface{}{"password": password})); err != nil {
+			t.Fatalf("WriteSecret(%s) error = %v", path, err)
+		}
+	}
+
+	// Copying the dev file over the prod one must not pass it off as prod
+	devFile := filepath.Join(client.directory, "app", "dev", "db.enc")
+	prodFile := filepath.Join(client.directory, "app", "prod", "db.enc")
+	raw, err := os.ReadFile(devFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(prodFile, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := client.GetSecret("app/prod/db"); err == nil {
+		t.Errorf("GetSecret() of a swapped file = %v, want an error", got.Data)
+	}
+	if _, err := client.GetSecret("app/dev/db"); err != nil {
+		t.Errorf("GetSecret() of the original file error = %v", err)
+	}
+}
+
+func TestNewClientKeyErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("VP_TEST_FILE_KEY", base64.StdEncoding.EncodeToString(testKey(1)[:16]))
+	if _, err := NewClient(&config.EnvironmentConfig{Directory: dir, KeyEnv: "VP_TEST_FILE_KEY"}); err == nil {
+		t.Errorf("NewClient() with a 16-byte key should fail")
+	}
+
+	t.Setenv("VP_TEST_FILE_KEY", base64.StdEncoding.EncodeToString(testKey(1)))
+	if _, err := NewClient(&config.EnvironmentConfig{Directory: dir, KeyEnv: "VP_TEST_FILE_KEY", Format: "toml"}); err == nil {
+		t.Errorf("NewClient() with an unknown format should fail")
+	}
+}
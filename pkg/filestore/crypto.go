@@ -0,0 +1,74 @@
+package filestore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// keySize is the AES-256 key length in bytes
+const keySize = 32
+
+// loadKey reads the base64-encoded AES-256 key from the named environment variable
+func loadKey(keyEnv string) ([]byte, error) {
+	if keyEnv == "" {
+		return nil, fmt.Errorf("key_env not specified in the environment config")
+	}
+
+	encoded := strings.TrimSpace(os.Getenv(keyEnv))
+	if encoded == "" {
+		return nil, fmt.Errorf("environment variable %s not set or empty", keyEnv)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("environment variable %s must hold a base64-encoded %d-byte key (e.g. from `openssl rand -base64 32`)", keyEnv, keySize)
+	}
+
+	return key, nil
+}
+
+// newAEAD creates the AES-GCM cipher for the key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM cipher: %w", err)
+	}
+
+	return aead, nil
+}
+
+// seal encrypts plaintext with a random nonce. The additional data binds the
+// ciphertext to its path so files can't be swapped around unnoticed.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) (nonce, ciphertext []byte, err error) {
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return nonce, aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts and authenticates a ciphertext produced by seal
+func open(aead cipher.AEAD, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt, wrong key or tampered file: %w", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,100 @@
+package filestore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, keySize)
+}
+
+func TestSealOpen(t *testing.T) {
+	aead, err := newAEAD(testKey(1))
+	if err != nil {
+		t.Fatalf("newAEAD() error = %v", err)
+	}
+
+	plaintext := []byte(`{"data":{"password":"hunter2"}}`)
+	nonce, ciphertext, err := seal(aead, plaintext, []byte("app/dev/db"))
+	if err != nil {
+		t.Fatalf("seal() error = %v", err)
+	}
+	if bytes.Contains(ciphertext, []byte("hunter2")) {
+		t.Errorf("seal() left the plaintext readable")
+	}
+
+	got, err := open(aead, nonce, ciphertext, []byte("app/dev/db"))
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("open() = %s, want %s", got, plaintext)
+	}
+
+	// Every seal uses a fresh nonce
+	again, _, err := seal(aead, plaintext, []byte("app/dev/db"))
+	if err != nil {
+		t.Fatalf("seal() error = %v", err)
+	}
+	if bytes.Equal(again, nonce) {
+		t.Errorf("seal() reused the nonce %x", nonce)
+	}
+
+	other, err := newAEAD(testKey(2))
+	if err != nil {
+		t.Fatalf("newAEAD() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		open func() ([]byte, error)
+	}{
+		{name: "wrong key", open: func() ([]byte, error) { return open(other, nonce, ciphertext, []byte("app/dev/db")) }},
+		{name: "other path", open: func() ([]byte, error) { return open(aead, nonce, ciphertext, []byte("app/prod/db")) }},
+		{name: "tampered ciphertext", open: func() ([]byte, error) {
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[0] ^= 0xff
+			return open(aead, nonce, tampered, []byte("app/dev/db"))
+		}},
+		{name: "short nonce", open: func() ([]byte, error) { return open(aead, nonce[:4], ciphertext, []byte("app/dev/db")) }},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.open(); err == nil {
+			t.Errorf("%s: open() should fail", tt.name)
+		}
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "32 bytes", value: base64.StdEncoding.EncodeToString(testKey(7))},
+		{name: "surrounding whitespace", value: "  " + base64.StdEncoding.EncodeToString(testKey(7)) + "\n"},
+		{name: "empty", value: "", wantErr: true},
+		{name: "16 bytes", value: base64.StdEncoding.EncodeToString(testKey(7)[:16]), wantErr: true},
+		{name: "33 bytes", value: base64.StdEncoding.EncodeToString(append(testKey(7), 7)), wantErr: true},
+		{name: "not base64", value: "not a key!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("VP_TEST_FILE_KEY", tt.value)
+		key, err := loadKey("VP_TEST_FILE_KEY")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: loadKey() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !bytes.Equal(key, testKey(7)) {
+			t.Errorf("%s: loadKey() = %x, want the decoded key", tt.name, key)
+		}
+	}
+
+	if _, err := loadKey(""); err == nil {
+		t.Errorf("loadKey() without key_env should fail")
+	}
+}
@@ -0,0 +1,173 @@
+package gcpsecretmanager
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// cloudPlatformScope grants access to Secret Manager
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// defaultTokenURI is used when the service account key doesn't name one
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
+)
+
+// tokenSource returns OAuth2 access tokens for Secret Manager requests
+type tokenSource interface {
+	Token() (string, error)
+}
+
+// staticToken is an access token obtained out of band, e.g. with `gcloud auth print-access-token`
+type staticToken string
+
+// Token returns the static access token
+func (t staticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+// serviceAccountKey is the subset of a service account JSON key file we use
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// serviceAccountTokenSource exchanges a signed JWT for access tokens and caches them
+type serviceAccountTokenSource struct {
+	key        *serviceAccountKey
+	signer     *rsa.PrivateKey
+	httpClient *http.Client
+
+	token     string
+	expiresAt time.Time
+}
+
+// newServiceAccountTokenSource loads a service account key file
+func newServiceAccountTokenSource(path string, httpClient *http.Client) (*serviceAccountTokenSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var key serviceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("unsupported credentials type %q, only service_account keys are supported", key.Type)
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode service account private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+
+	signer, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account private key is not an RSA key")
+	}
+
+	if key.TokenURI == "" {
+		key.TokenURI = defaultTokenURI
+	}
+
+	return &serviceAccountTokenSource{
+		key:        &key,
+		signer:     signer,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Token returns a valid access token, requesting a new one when the cached one is about to expire
+func (s *serviceAccountTokenSource) Token() (string, error) {
+	if s.token != "" && time.Now().Add(time.Minute).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	assertion, err := s.signAssertion()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.httpClient.PostForm(s.key.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to request GCP access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode GCP token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		return "", fmt.Errorf("failed to authenticate with GCP (status %d): %s %s", resp.StatusCode, result.Error, result.Description)
+	}
+
+	s.token = result.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// signAssertion builds the RS256-signed JWT used in the token request
+func (s *serviceAccountTokenSource) signAssertion() (string, error) {
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": s.key.PrivateKeyID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT header: %w", err)
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.key.ClientEmail,
+		"scope": cloudPlatformScope,
+		"aud":   s.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.signer, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
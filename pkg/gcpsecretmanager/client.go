@@ -0,0 +1,394 @@
+package gcpsecretmanager
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// defaultEndpoint is the public Secret Manager API endpoint
+const defaultEndpoint = "https://secretmanager.googleapis.com"
+
+// latestVersion is the alias GCP resolves to the newest enabled version
+const latestVersion = "latest"
+
+// pathSeparator replaces "/" in paths, since secret IDs only allow letters, digits, dashes and underscores
+const pathSeparator = "--"
+
+// validSecretID matches the secret IDs Secret Manager accepts
+var validSecretID = regexp.MustCompile(`^[0-9a-zA-Z_-]{1,255}$`)
+
+// Client handles interactions with the GCP Secret Manager REST API
+type Client struct {
+	endpoint   string
+	projectID  string
+	httpClient *http.Client
+	tokens     tokenSource
+}
+
+// secretResource is a secret as returned by the API, without its payload
+type secretResource struct {
+	Name       string            `json:"name"`
+	CreateTime time.Time         `json:"createTime"`
+	Labels     map[string]string `json:"labels"`
+}
+
+// versionResource is a secret version as returned by the API
+type versionResource struct {
+	Name       string    `json:"name"`
+	CreateTime time.Time `json:"createTime"`
+	State      string    `json:"state"`
+}
+
+// accessResponse is the result of accessing a secret version
+type accessResponse struct {
+	Name    string `json:"name"`
+	Payload struct {
+		Data string `json:"data"`
+	} `json:"payload"`
+}
+
+// listSecretsResponse is one page of the secret listing
+type listSecretsResponse struct {
+	Secrets       []secretResource `json:"secrets"`
+	NextPageToken string           `json:"nextPageToken"`
+}
+
+// errorResponse is the error body returned by Google APIs
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// NewClient creates a Secret Manager client for the environment's project.
+// It authenticates with the service account key in credentials_file (or
+// GOOGLE_APPLICATION_CREDENTIALS), or with an access token read from token_env.
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	if envConfig.ProjectID == "" {
+		return nil, fmt.Errorf("project_id is required for GCP Secret Manager")
+	}
+
+	endpoint := envConfig.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
+	var tokens tokenSource
+	credentialsFile := envConfig.CredentialsFile
+	if credentialsFile == "" && envConfig.TokenEnv == "" {
+		credentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+
+	if credentialsFile != "" {
+		source, err := newServiceAccountTokenSource(credentialsFile, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		tokens = source
+	} else if envConfig.TokenEnv != "" {
+		token := strings.TrimSpace(os.Getenv(envConfig.TokenEnv))
+		if token == "" {
+			return nil, fmt.Errorf("environment variable %s not set or empty", envConfig.TokenEnv)
+		}
+		tokens = staticToken(token)
+	} else {
+		return nil, fmt.Errorf("credentials_file or token_env is required for GCP Secret Manager")
+	}
+
+	return &Client{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		projectID:  envConfig.ProjectID,
+		httpClient: httpClient,
+		tokens:     tokens,
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreGCPSecretManager
+}
+
+// secretID converts a store path into a secret ID and version.
+// A path may pin a version with "path@5"; otherwise the latest version is used.
+func secretID(path string) (string, string, error) {
+	path, version := store.SplitVersion(path)
+	if version == "" {
+		version = latestVersion
+	}
+
+	id := strings.ReplaceAll(strings.Trim(path, "/"), "/", pathSeparator)
+	if !validSecretID.MatchString(id) {
+		return "", "", fmt.Errorf("invalid GCP secret ID %q: only letters, digits, dashes and underscores are allowed", id)
+	}
+	return id, version, nil
+}
+
+// secretPath converts a secret ID back into a store path
+func secretPath(id string) string {
+	return strings.ReplaceAll(id, pathSeparator, "/")
+}
+
+// secretURL builds the API URL of a secret, followed by the given suffix
+func (c *Client) secretURL(id, suffix string) string {
+	return fmt.Sprintf("%s/v1/projects/%s/secrets/%s%s", c.endpoint, url.PathEscape(c.projectID), url.PathEscape(id), suffix)
+}
+
+// do sends an authenticated request and decodes the JSON response into out.
+// A 404 response is reported as store.ErrSecretNotFound.
+func (c *Client) do(method, requestURL string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	token, err := c.tokens.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to GCP Secret Manager failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return store.ErrSecretNotFound
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("gcp secret manager returned status %d: %s %s", resp.StatusCode, apiErr.Error.Status, apiErr.Error.Message)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode GCP Secret Manager response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetSecret accesses the latest or pinned version and parses its payload with format detection
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	id, version, err := secretID(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result accessResponse
+	if err := c.do(http.MethodGet, c.secretURL(id, "/versions/"+url.PathEscape(version)+":access"), nil, &result); err != nil {
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret payload: %w", err)
+	}
+
+	// A plain string secret is the secret itself
+	secret := store.ParseSecretString(string(payload))
+	secret.MarkOpaqueSensitive()
+
+	return secret, nil
+}
+
+// WriteSecret adds a new version to the secret, creating the secret first if needed
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	if _, version := store.SplitVersion(path); version != "" {
+		return fmt.Errorf("cannot write to a pinned version: %s", path)
+	}
+
+	id, _, err := secretID(path)
+	if err != nil {
+		return err
+	}
+
+	value, err := store.MarshalSecretString(secret)
+	if err != nil {
+		return err
+	}
+
+	request := map[string]interface{}{
+		"payload": map[string]string{
+			"data": base64.StdEncoding.EncodeToString([]byte(value)),
+		},
+	}
+
+	err = c.do(http.MethodPost, c.secretURL(id, ":addVersion"), request, nil)
+	if err != nil && store.IsNotFound(err) {
+		// The secret itself doesn't exist yet
+		create := map[string]interface{}{
+			"replication": map[string]interface{}{"automatic": map[string]interface{}{}},
+		}
+		createURL := fmt.Sprintf("%s/v1/projects/%s/secrets?secretId=%s", c.endpoint, url.PathEscape(c.projectID), url.QueryEscape(id))
+		if err := c.do(http.MethodPost, createURL, create, nil); err != nil {
+			return fmt.Errorf("failed to create secret: %w", err)
+		}
+
+		err = c.do(http.MethodPost, c.secretURL(id, ":addVersion"), request, nil)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write secret: %w", err)
+	}
+
+	return nil
+}
+
+// ListSecrets lists secret paths under prefix, folding deeper names into "/"-suffixed folders
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+
+	seen := make(map[string]bool)
+	var names []string
+
+	pageToken := ""
+	for {
+		listURL := fmt.Sprintf("%s/v1/projects/%s/secrets?pageSize=250", c.endpoint, url.PathEscape(c.projectID))
+		if pageToken != "" {
+			listURL += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+
+		var page listSecretsResponse
+		if err := c.do(http.MethodGet, listURL, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list secrets: %w", err)
+		}
+
+		for _, entry := range page.Secrets {
+			// The resource name ends with the secret ID
+			name := secretPath(entry.Name[strings.LastIndex(entry.Name, "/")+1:])
+
+			rest := name
+			if prefix != "" {
+				if !strings.HasPrefix(name, prefix+"/") {
+					continue
+				}
+				rest = strings.TrimPrefix(name, prefix+"/")
+			}
+
+			if idx := strings.Index(rest, "/"); idx >= 0 {
+				name = strings.TrimPrefix(prefix+"/"+rest[:idx+1], "/")
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeleteSecret deletes the secret and all of its versions
+func (c *Client) DeleteSecret(path string) error {
+	id, _, err := secretID(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.do(http.MethodDelete, c.secretURL(id, ""), nil, nil); err != nil {
+		if store.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+
+	return nil
+}
+
+// SecretExists checks whether the secret (or the pinned version) exists
+func (c *Client) SecretExists(path string) (bool, error) {
+	_, err := c.GetMetadata(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetMetadata returns the resolved version, timestamps and labels of the secret
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	id, version, err := secretID(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret secretResource
+	if err := c.do(http.MethodGet, c.secretURL(id, ""), nil, &secret); err != nil {
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	var versionInfo versionResource
+	if err := c.do(http.MethodGet, c.secretURL(id, "/versions/"+url.PathEscape(version)), nil, &versionInfo); err != nil {
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to get secret version: %w", err)
+	}
+
+	metadata := &store.Metadata{
+		Path:        path,
+		Version:     versionInfo.Name[strings.LastIndex(versionInfo.Name, "/")+1:],
+		CreatedTime: secret.CreateTime,
+		UpdatedTime: versionInfo.CreateTime,
+		Labels:      make(map[string]string),
+	}
+
+	for key, value := range secret.Labels {
+		metadata.Labels[key] = value
+	}
+	if versionInfo.State != "" {
+		metadata.Labels["state"] = versionInfo.State
+	}
+
+	return metadata, nil
+}
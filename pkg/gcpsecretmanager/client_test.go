@@ -0,0 +1,340 @@
+package gcpsecretmanager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// fakeSecretManager serves the parts of the Secret Manager API the client uses.
+// Each secret holds its payloads, version n being versions[n-1].
+type fakeSecretManager struct {
+	t        *testing.T
+	server   *httptest.Server
+	mu       sync.Mutex
+	secrets  map[string][]string
+	pageSize int
+}
+
+func newFakeSecretManager(t *testing.T) *fakeSecretManager {
+	f := &fakeSecretManager{t: t, secrets: make(map[string][]string), pageSize: 2}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeSecretManager) client() *Client {
+	return &Client{
+		endpoint:   f.server.URL,
+		projectID:  "my-project",
+		httpClient: f.server.Client(),
+		tokens:     staticToken("token"),
+	}
+}
+
+func (f *fakeSecretManager) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const base = "/v1/projects/my-project/secrets"
+	if !strings.HasPrefix(r.URL.Path, base) {
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rest := strings.TrimPrefix(r.URL.Path, base)
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		f.list(w, r)
+	case rest == "" && r.Method == http.MethodPost:
+		id := r.URL.Query().Get("secretId")
+		if _, ok := f.secrets[id]; ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.secrets[id] = nil
+		f.writeJSON(w, secretResource{Name: "projects/my-project/secrets/" + id})
+	case strings.HasSuffix(rest, ":addVersion"):
+		id := strings.TrimSuffix(strings.TrimPrefix(rest, "/"), ":addVersion")
+		versions, ok := f.secrets[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var request struct {
+			Payload struct {
+				Data string `json:"data"`
+			} `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			f.t.Errorf("failed to decode addVersion body: %v", err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(request.Payload.Data)
+		if err != nil {
+			f.t.Errorf("addVersion payload is not base64: %v", err)
+		}
+		f.secrets[id] = append(versions, string(payload))
+		f.writeJSON(w, versionResource{Name: fmt.Sprintf("projects/my-project/secrets/%s/versions/%d", id, len(f.secrets[id]))})
+	default:
+		f.secret(w, r, strings.TrimPrefix(rest, "/"))
+	}
+}
+
+// secret serves requests on a single secret or one of its versions
+func (f *fakeSecretManager) secret(w http.ResponseWriter, r *http.Request, rest string) {
+	parts := strings.SplitN(rest, "/versions/", 2)
+	versions, ok := f.secrets[parts[0]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodDelete:
+			delete(f.secrets, parts[0])
+		default:
+			f.writeJSON(w, secretResource{Name: "projects/my-project/secrets/" + parts[0], Labels: map[string]string{"team": "payments"}})
+		}
+		return
+	}
+
+	selector := strings.TrimSuffix(parts[1], ":access")
+	number := len(versions)
+	if selector != latestVersion {
+		if _, err := fmt.Sscanf(selector, "%d", &number); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if number < 1 || number > len(versions) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	name := fmt.Sprintf("projects/my-project/secrets/%s/versions/%d", parts[0], number)
+	if strings.HasSuffix(parts[1], ":access") {
+		var result accessResponse
+		result.Name = name
+		result.Payload.Data = base64.StdEncoding.EncodeToString([]byte(versions[number-1]))
+		f.writeJSON(w, result)
+		return
+	}
+	f.writeJSON(w, versionResource{Name: name, State: "ENABLED"})
+}
+
+// list returns the secrets in pages of pageSize, linked with nextPageToken
+func (f *fakeSecretManager) list(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	for id := range f.secrets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	start := 0
+	if token := r.URL.Query().Get("pageToken"); token != "" {
+		for start < len(ids) && ids[start] != token {
+			start++
+		}
+	}
+
+	var page listSecretsResponse
+	for i := start; i < len(ids) && i < start+f.pageSize; i++ {
+		page.Secrets = append(page.Secrets, secretResource{Name: "projects/my-project/secrets/" + ids[i]})
+	}
+	if start+f.pageSize < len(ids) {
+		page.NextPageToken = ids[start+f.pageSize]
+	}
+	f.writeJSON(w, page)
+}
+
+func (f *fakeSecretManager) writeJSON(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+func TestWriteCreatesSecretAndAddsVersions(t *testing.T) {
+	fake := newFakeSecretManager(t)
+	client := fake.client()
+
+	first := store.NewSecret(map[string]interface{}{"username": "app", "password": "hunter2"})
+	if err := client.WriteSecret("app/dev/db", first); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	second := store.NewSecret(map[string]interface{}{"username": "app", "password": "correct-horse"})
+	if err := client.WriteSecret("app/dev/db", second); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	versions, ok := fake.secrets["app--dev--db"]
+	if !ok || len(versions) != 2 {
+		t.Fatalf("stored %v, want two versions under the mapped ID", fake.secrets)
+	}
+
+	got, err := client.GetSecret("app/dev/db")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if !got.IsJSON || !reflect.DeepEqual(got.Data, second.Data) {
+		t.Errorf("GetSecret() = %v, want the latest version %v", got.Data, second.Data)
+	}
+	if got.IsSensitive("password") {
+		t.Errorf("keys of a JSON secret should be left to the key rules")
+	}
+
+	pinned, err := client.GetSecret("app/dev/db@1")
+	if err != nil {
+		t.Fatalf("GetSecret(@1) error = %v", err)
+	}
+	if !reflect.DeepEqual(pinned.Data, first.Data) {
+		t.Errorf("GetSecret(@1) = %v, want the first version %v", pinned.Data, first.Data)
+	}
+
+	if _, err := client.GetSecret("app/dev/db@3"); !store.IsNotFound(err) {
+		t.Errorf("GetSecret(@3) error = %v, want not found", err)
+	}
+
+	if err := client.WriteSecret("app/dev/db@1", first); err == nil {
+		t.Errorf("WriteSecret() to a pinned version should fail")
+	}
+}
+
+func TestPlainStringSecret(t *testing.T) {
+	fake := newFakeSecretManager(t)
+	client := fake.client()
+
+	fake.secrets["api-key"] = []string{"s3cr3t"}
+
+	got, err := client.GetSecret("api-key")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if got.IsJSON || got.Data["value"] != "s3cr3t" || !got.IsSensitive("value") {
+		t.Errorf("GetSecret() = %v (json %v, sensitive %v), want the sensitive plain value", got.Data, got.IsJSON, got.Sensitive)
+	}
+
+	if err := client.WriteSecret("api-key", got); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if versions := fake.secrets["api-key"]; len(versions) != 2 || versions[1] != "s3cr3t" {
+		t.Errorf("stored %v, want the plain value added as a new version", versions)
+	}
+}
+
+func TestGetSecretNotFound(t *testing.T) {
+	client := newFakeSecretManager(t).client()
+
+	if _, err := client.GetSecret("app/missing"); !store.IsNotFound(err) {
+		t.Fatalf("GetSecret() error = %v, want not found", err)
+	}
+
+	exists, err := client.SecretExists("app/missing")
+	if err != nil || exists {
+		t.Errorf("SecretExists() = %v, %v, want false", exists, err)
+	}
+
+	if err := client.DeleteSecret("app/missing"); !store.IsNotFound(err) {
+		t.Errorf("DeleteSecret() error = %v, want not found", err)
+	}
+}
+
+func TestSecretID(t *testing.T) {
+	tests := []struct {
+		path    string
+		id      string
+		version string
+		wantErr bool
+	}{
+		{path: "app/dev/db", id: "app--dev--db", version: latestVersion},
+		{path: "/app/dev/db/", id: "app--dev--db", version: latestVersion},
+		{path: "app/dev/db@5", id: "app--dev--db", version: "5"},
+		{path: "app/dev/db@latest", id: "app--dev--db", version: latestVersion},
+		{path: "team@corp/db", wantErr: true},
+		{path: "app/dev.db", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		id, version, err := secretID(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("secretID(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (id != tt.id || version != tt.version) {
+			t.Errorf("secretID(%q) = %q, %q, want %q, %q", tt.path, id, version, tt.id, tt.version)
+		}
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	fake := newFakeSecretManager(t)
+	client := fake.client()
+
+	for _, id := range []string{"app--dev--db", "app--dev--api", "app--prod--db", "app--shared", "other"} {
+		fake.secrets[id] = []string{"{}"}
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"app/", "other"}},
+		{prefix: "app", want: []string{"app/dev/", "app/prod/", "app/shared"}},
+		{prefix: "/app/dev/", want: []string{"app/dev/api", "app/dev/db"}},
+		{prefix: "missing", want: nil},
+	}
+
+	for _, tt := range tests {
+		got, err := client.ListSecrets(tt.prefix)
+		if err != nil {
+			t.Fatalf("ListSecrets(%q) error = %v", tt.prefix, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListSecrets(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestGetMetadata(t *testing.T) {
+	fake := newFakeSecretManager(t)
+	client := fake.client()
+
+	fake.secrets["app--db"] = []string{"one", "two", "three"}
+
+	tests := []struct {
+		path    string
+		version string
+	}{
+		{path: "app/db", version: "3"},
+		{path: "app/db@2", version: "2"},
+	}
+
+	for _, tt := range tests {
+		metadata, err := client.GetMetadata(tt.path)
+		if err != nil {
+			t.Fatalf("GetMetadata(%q) error = %v", tt.path, err)
+		}
+		if metadata.Version != tt.version {
+			t.Errorf("GetMetadata(%q).Version = %q, want %q", tt.path, metadata.Version, tt.version)
+		}
+		if metadata.Labels["team"] != "payments" || metadata.Labels["state"] != "ENABLED" {
+			t.Errorf("GetMetadata(%q).Labels = %v, want the secret labels and version state", tt.path, metadata.Labels)
+		}
+	}
+}
@@ -0,0 +1,514 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// Object kinds, as they appear in API paths
+const (
+	kindSecret    = "secrets"
+	kindConfigMap = "configmaps"
+)
+
+// Client reads and writes Kubernetes Secrets and ConfigMaps.
+// Paths are "namespace/name", or "namespace/secrets/name" and
+// "namespace/configmaps/name" to pick the kind explicitly.
+type Client struct {
+	conn      *connection
+	namespace string
+}
+
+// objectRef identifies a Secret or ConfigMap; an empty kind means either
+type objectRef struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+// object is the subset of a Secret or ConfigMap we read and create
+type object struct {
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Kind       string            `json:"kind,omitempty"`
+	Metadata   objectMeta        `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+	BinaryData map[string]string `json:"binaryData,omitempty"`
+}
+
+// objectMeta is the subset of object metadata we use
+type objectMeta struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace,omitempty"`
+	ResourceVersion   string            `json:"resourceVersion,omitempty"`
+	CreationTimestamp string            `json:"creationTimestamp,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	ManagedFields     []struct {
+		Time string `json:"time"`
+	} `json:"managedFields,omitempty"`
+}
+
+// objectList is the result of listing Secrets, ConfigMaps or Namespaces
+type objectList struct {
+	Items    []object `json:"items"`
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+}
+
+// statusResponse is the error body returned by the API server
+type statusResponse struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+// NewClient connects to the cluster of the environment's kubeconfig context
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	conn, err := loadConnection(envConfig.Kubeconfig, envConfig.KubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes connection: %w", err)
+	}
+
+	if envConfig.Endpoint != "" {
+		conn.server = strings.TrimRight(envConfig.Endpoint, "/")
+	}
+
+	if conn.server == "" {
+		return nil, fmt.Errorf("no Kubernetes API server configured")
+	}
+
+	// The namespace in the config wins over the one of the context
+	namespace := envConfig.KubeNamespace
+	if namespace == "" {
+		namespace = conn.namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return &Client{
+		conn:      conn,
+		namespace: namespace,
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreKubernetes
+}
+
+// parsePath splits a store path into the object it refers to
+func (c *Client) parsePath(path string) (objectRef, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch len(parts) {
+	case 1:
+		return objectRef{namespace: c.namespace, name: parts[0]}, nil
+	case 2:
+		return objectRef{namespace: parts[0], name: parts[1]}, nil
+	case 3:
+		if parts[1] != kindSecret && parts[1] != kindConfigMap {
+			return objectRef{}, fmt.Errorf("invalid Kubernetes path %s: kind must be %s or %s", path, kindSecret, kindConfigMap)
+		}
+		return objectRef{namespace: parts[0], kind: parts[1], name: parts[2]}, nil
+	default:
+		return objectRef{}, fmt.Errorf("invalid Kubernetes path %s: expected namespace/name", path)
+	}
+}
+
+// objectURL builds the API URL of an object, or of its collection when name is empty
+func (c *Client) objectURL(namespace, kind, name string) string {
+	u := fmt.Sprintf("%s/api/v1/namespaces/%s/%s", c.conn.server, url.PathEscape(namespace), kind)
+	if name != "" {
+		u += "/" + url.PathEscape(name)
+	}
+	return u
+}
+
+// do sends an authenticated request and decodes the JSON response into out.
+// A 404 response is reported as store.ErrSecretNotFound.
+func (c *Client) do(method, requestURL, contentType string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if c.conn.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.conn.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.conn.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to Kubernetes API failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return store.ErrSecretNotFound
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var status statusResponse
+		_ = json.NewDecoder(resp.Body).Decode(&status)
+		return fmt.Errorf("kubernetes API returned status %d: %s %s", resp.StatusCode, status.Reason, status.Message)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode Kubernetes API response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getObject fetches the object a path refers to. When the kind isn't given,
+// a Secret is looked up first and a ConfigMap second.
+func (c *Client) getObject(path string) (*object, objectRef, error) {
+	ref, err := c.parsePath(path)
+	if err != nil {
+		return nil, ref, err
+	}
+
+	kinds := []string{ref.kind}
+	if ref.kind == "" {
+		kinds = []string{kindSecret, kindConfigMap}
+	}
+
+	for _, kind := range kinds {
+		var obj object
+		err := c.do(http.MethodGet, c.objectURL(ref.namespace, kind, ref.name), "", nil, &obj)
+		if err == nil {
+			ref.kind = kind
+			return &obj, ref, nil
+		}
+		if !store.IsNotFound(err) {
+			return nil, ref, fmt.Errorf("failed to get %s: %w", kind, err)
+		}
+	}
+
+	return nil, ref, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+}
+
+// GetSecret reads a Secret (base64-decoded, all keys sensitive) or a ConfigMap
+// (plain configuration). An object whose only entry isn't UTF-8 text, such as a
+// keystore, is read as a binary secret; next to other keys such an entry keeps
+// its base64 value and is flagged as a binary key.
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	obj, ref, err := c.getObject(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Secret data and ConfigMap binaryData are base64-encoded
+	encoded := obj.BinaryData
+	plain := obj.Data
+	if ref.kind == kindSecret {
+		encoded, plain = obj.Data, nil
+	}
+
+	decoded := make(map[string][]byte, len(encoded))
+	for key, value := range encoded {
+		content, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key %s of %s %s: %w", key, ref.kind, path, err)
+		}
+		decoded[key] = content
+	}
+
+	secret := store.NewSecret(nil)
+	for key, value := range plain {
+		secret.Data[key] = value
+	}
+	for key, content := range decoded {
+		if utf8.Valid(content) {
+			secret.Data[key] = string(content)
+			continue
+		}
+		if len(decoded)+len(plain) > 1 {
+			secret.Data[key] = base64.StdEncoding.EncodeToString(content)
+			secret.BinaryKeys[key] = true
+			continue
+		}
+		secret = store.NewBinarySecret(content)
+	}
+
+	if ref.kind == kindSecret {
+		secret.MarkAllSensitive()
+	}
+	return secret, nil
+}
+
+// SupportsBinary reports that binary secrets are kept as the single key of an object
+func (c *Client) SupportsBinary() bool {
+	return true
+}
+
+// WriteSecret updates the existing Secret or ConfigMap at path, or creates a Secret
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	existing, ref, err := c.getObject(path)
+	if err != nil && !store.IsNotFound(err) {
+		return err
+	}
+
+	// New objects are Secrets unless the path asks for a ConfigMap
+	if ref.kind == "" {
+		ref.kind = kindSecret
+	}
+
+	data := make(map[string]string)
+	binaryData := make(map[string]string)
+	if secret.Binary {
+		content, err := secret.BinaryContent()
+		if err != nil {
+			return err
+		}
+
+		// Keep the key name of an object that already holds a single entry
+		key := binaryKey(existing)
+		if ref.kind == kindSecret {
+			data[key] = base64.StdEncoding.EncodeToString(content)
+		} else {
+			binaryData[key] = base64.StdEncoding.EncodeToString(content)
+		}
+	} else {
+		for key, value := range secret.Data {
+			valueStr := store.StringValue(value)
+			if secret.IsBinaryKey(key) {
+				// Binary keys already hold the base64 of their bytes
+				if _, err := base64.StdEncoding.DecodeString(valueStr); err != nil {
+					return fmt.Errorf("failed to decode binary key %s: %w", key, err)
+				}
+				if ref.kind == kindSecret {
+					data[key] = valueStr
+				} else {
+					binaryData[key] = valueStr
+				}
+				continue
+			}
+			if ref.kind == kindSecret {
+				valueStr = base64.StdEncoding.EncodeToString([]byte(valueStr))
+			}
+			data[key] = valueStr
+		}
+	}
+
+	if existing == nil {
+		obj := object{
+			APIVersion: "v1",
+			Metadata:   objectMeta{Name: ref.name, Namespace: ref.namespace},
+			Data:       data,
+		}
+		if ref.kind == kindSecret {
+			obj.Kind = "Secret"
+			obj.Type = "Opaque"
+		} else {
+			obj.Kind = "ConfigMap"
+			obj.BinaryData = binaryData
+		}
+
+		if err := c.do(http.MethodPost, c.objectURL(ref.namespace, ref.kind, ""), "application/json", obj, nil); err != nil {
+			return fmt.Errorf("failed to create %s: %w", ref.kind, err)
+		}
+		return nil
+	}
+
+	// A merge patch only touches the data; keys missing from the new data are removed
+	patch := map[string]interface{}{"data": mergePatch(existing.Data, data)}
+	if ref.kind == kindConfigMap && (len(existing.BinaryData) > 0 || len(binaryData) > 0) {
+		patch["binaryData"] = mergePatch(existing.BinaryData, binaryData)
+	}
+
+	if err := c.do(http.MethodPatch, c.objectURL(ref.namespace, ref.kind, ref.name), "application/merge-patch+json", patch, nil); err != nil {
+		return fmt.Errorf("failed to update %s: %w", ref.kind, err)
+	}
+
+	return nil
+}
+
+// mergePatch sets the entries of data and removes the other entries of existing
+func mergePatch(existing, data map[string]string) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key, value := range data {
+		patch[key] = value
+	}
+	for key := range existing {
+		if _, ok := data[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// binaryKey picks the key a binary secret is written under: the only key of
+// the existing object, or "value" like in other stores
+func binaryKey(existing *object) string {
+	if existing != nil && len(existing.Data)+len(existing.BinaryData) == 1 {
+		for key := range existing.Data {
+			return key
+		}
+		for key := range existing.BinaryData {
+			return key
+		}
+	}
+	return "value"
+}
+
+// listNames lists the names of the objects of one kind in a namespace
+func (c *Client) listNames(namespace, kind string) ([]string, error) {
+	var names []string
+	continueToken := ""
+	for {
+		listURL := c.objectURL(namespace, kind, "") + "?limit=500"
+		if continueToken != "" {
+			listURL += "&continue=" + url.QueryEscape(continueToken)
+		}
+
+		var list objectList
+		if err := c.do(http.MethodGet, listURL, "", nil, &list); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+
+		for _, item := range list.Items {
+			names = append(names, item.Metadata.Name)
+		}
+
+		if list.Metadata.Continue == "" {
+			return names, nil
+		}
+		continueToken = list.Metadata.Continue
+	}
+}
+
+// ListSecrets lists namespaces as folders at the top level, and the
+// Secrets and ConfigMaps of a namespace below it
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+	parts := strings.Split(prefix, "/")
+
+	var names []string
+	switch {
+	case prefix == "":
+		var list objectList
+		if err := c.do(http.MethodGet, c.conn.server+"/api/v1/namespaces", "", nil, &list); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		for _, item := range list.Items {
+			names = append(names, item.Metadata.Name+"/")
+		}
+	case len(parts) == 1:
+		seen := make(map[string]bool)
+		for _, kind := range []string{kindSecret, kindConfigMap} {
+			kindNames, err := c.listNames(parts[0], kind)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range kindNames {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, parts[0]+"/"+name)
+				}
+			}
+		}
+	case len(parts) == 2 && (parts[1] == kindSecret || parts[1] == kindConfigMap):
+		kindNames, err := c.listNames(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range kindNames {
+			names = append(names, prefix+"/"+name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid Kubernetes prefix %s: expected a namespace", prefix)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeleteSecret deletes the Secret or ConfigMap at path
+func (c *Client) DeleteSecret(path string) error {
+	_, ref, err := c.getObject(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.do(http.MethodDelete, c.objectURL(ref.namespace, ref.kind, ref.name), "", nil, nil); err != nil {
+		if store.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return fmt.Errorf("failed to delete %s: %w", ref.kind, err)
+	}
+
+	return nil
+}
+
+// SecretExists checks whether a Secret or ConfigMap exists at path
+func (c *Client) SecretExists(path string) (bool, error) {
+	_, _, err := c.getObject(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetMetadata returns the resource version, timestamps and labels of the object
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	obj, ref, err := c.getObject(path)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &store.Metadata{
+		Path:    path,
+		Version: obj.Metadata.ResourceVersion,
+		Labels:  map[string]string{"kind": ref.kind},
+	}
+
+	if created, err := time.Parse(time.RFC3339, obj.Metadata.CreationTimestamp); err == nil {
+		metadata.CreatedTime = created
+		metadata.UpdatedTime = created
+	}
+
+	// The last managed field update is the closest thing to a modification time
+	for _, field := range obj.Metadata.ManagedFields {
+		if updated, err := time.Parse(time.RFC3339, field.Time); err == nil && updated.After(metadata.UpdatedTime) {
+			metadata.UpdatedTime = updated
+		}
+	}
+
+	for key, value := range obj.Metadata.Labels {
+		metadata.Labels[key] = value
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,462 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// fakeCluster serves the parts of the core API the client uses
+type fakeCluster struct {
+	t        *testing.T
+	server   *httptest.Server
+	mu       sync.Mutex
+	objects  map[string]map[string]object // kind -> namespace/name -> object
+	pageSize int
+	requests []string // method and path of each request
+	lists    []string // continue token of each list request
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	f := &fakeCluster{
+		t:        t,
+		objects:  map[string]map[string]object{kindSecret: {}, kindConfigMap: {}},
+		pageSize: 2,
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeCluster) client() *Client {
+	return &Client{
+		conn: &connection{
+			server:     f.server.URL,
+			token:      "token",
+			httpClient: f.server.Client(),
+		},
+		namespace: "default",
+	}
+}
+
+// add stores an object as if it had been created by another client
+func (f *fakeCluster) add(namespace, kind, name string, data, binaryData map[string]string) {
+	f.objects[kind][namespace+"/"+name] = object{
+		Metadata:   objectMeta{Name: name, Namespace: namespace, ResourceVersion: "1"},
+		Data:       data,
+		BinaryData: binaryData,
+	}
+}
+
+func (f *fakeCluster) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.URL.Path == "/api/v1/namespaces" {
+		seen := make(map[string]bool)
+		var list objectList
+		for _, objects := range f.objects {
+			for _, obj := range objects {
+				if !seen[obj.Metadata.Namespace] {
+					seen[obj.Metadata.Namespace] = true
+					list.Items = append(list.Items, object{Metadata: objectMeta{Name: obj.Metadata.Namespace}})
+				}
+			}
+		}
+		f.respond(w, list)
+		return
+	}
+
+	// /api/v1/namespaces/{namespace}/{kind}[/{name}]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/"), "/")
+	if len(parts) < 2 || f.objects[parts[1]] == nil {
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	namespace, kind := parts[0], parts[1]
+	objects := f.objects[kind]
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			f.list(w, r, namespace, objects)
+		case http.MethodPost:
+			var obj object
+			if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+				f.t.Errorf("failed to decode created object: %v", err)
+			}
+			objects[namespace+"/"+obj.Metadata.Name] = obj
+			f.respond(w, obj)
+		default:
+			f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		return
+	}
+
+	key := namespace + "/" + parts[2]
+	obj, ok := objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(statusResponse{Reason: "NotFound"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		f.respond(w, obj)
+	case http.MethodPatch:
+		if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+			f.t.Errorf("patch content type = %q, want a merge patch", r.Header.Get("Content-Type"))
+		}
+		var patch map[string]map[string]*string
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			f.t.Errorf("failed to decode patch: %v", err)
+		}
+		obj.Data = applyMergePatch(obj.Data, patch["data"])
+		obj.BinaryData = applyMergePatch(obj.BinaryData, patch["binaryData"])
+		objects[key] = obj
+		f.respond(w, obj)
+	case http.MethodDelete:
+		delete(objects, key)
+		f.respond(w, statusResponse{})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	}
+}
+
+// list returns one page of the objects of a namespace, pageSize at a time
+func (f *fakeCluster) list(w http.ResponseWriter, r *http.Request, namespace string, objects map[string]object) {
+	if r.URL.Query().Get("limit") == "" {
+		f.t.Errorf("list request %s without a limit", r.URL)
+	}
+
+	var names []string
+	for key, obj := range objects {
+		if strings.HasPrefix(key, namespace+"/") {
+			names = append(names, obj.Metadata.Name)
+		}
+	}
+	sort.Strings(names)
+
+	token := r.URL.Query().Get("continue")
+	f.lists = append(f.lists, token)
+	start := 0
+	if token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+
+	var list objectList
+	end := start + f.pageSize
+	if end < len(names) {
+		list.Metadata.Continue = strconv.Itoa(end)
+	} else {
+		end = len(names)
+	}
+	for _, name := range names[start:end] {
+		list.Items = append(list.Items, object{Metadata: objectMeta{Name: name}})
+	}
+	f.respond(w, list)
+}
+
+func (f *fakeCluster) respond(w http.ResponseWriter, body interface{}) {
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		f.t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+// applyMergePatch sets the entries of patch and removes those set to null
+func applyMergePatch(data map[string]string, patch map[string]*string) map[string]string {
+	if patch == nil {
+		return data
+	}
+	result := make(map[string]string)
+	for key, value := range data {
+		result[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(result, key)
+		} else {
+			result[key] = *value
+		}
+	}
+	return result
+}
+
+func encode(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func TestParsePath(t *testing.T) {
+	client := &Client{namespace: "default"}
+
+	tests := []struct {
+		path    string
+		want    objectRef
+		wantErr bool
+	}{
+		{path: "app", want: objectRef{namespace: "default", name: "app"}},
+		{path: "team/app", want: objectRef{namespace: "team", name: "app"}},
+		{path: "/team/app/", want: objectRef{namespace: "team", name: "app"}},
+		{path: "team/secrets/app", want: objectRef{namespace: "team", kind: kindSecret, name: "app"}},
+		{path: "team/configmaps/app", want: objectRef{namespace: "team", kind: kindConfigMap, name: "app"}},
+		{path: "team/pods/app", wantErr: true},
+		{path: "team/secrets/app/extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := client.parsePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parsePath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGetSecretLooksUpSecretThenConfigMap(t *testing.T) {
+	fake := newFakeCluster(t)
+	client := fake.client()
+
+	fake.add("team", kindSecret, "app", map[string]string{"password": encode("hunter2")}, nil)
+	fake.add("team", kindConfigMap, "app", map[string]string{"log_level": "debug"}, nil)
+	fake.add("team", kindConfigMap, "settings", map[string]string{"port": "8080"}, nil)
+
+	tests := []struct {
+		path      string
+		want      map[string]interface{}
+		sensitive bool
+	}{
+		{path: "team/app", want: map[string]interface{}{"password": "hunter2"}, sensitive: true},
+		{path: "team/configmaps/app", want: map[string]interface{}{"log_level": "debug"}, sensitive: false},
+		{path: "team/settings", want: map[string]interface{}{"port": "8080"}, sensitive: false},
+	}
+
+	for _, tt := range tests {
+		got, err := client.GetSecret(tt.path)
+		if err != nil {
+			t.Fatalf("GetSecret(%s) error = %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got.Data, tt.want) {
+			t.Errorf("GetSecret(%s) = %v, want %v", tt.path, got.Data, tt.want)
+		}
+		for key := range got.Data {
+			if got.IsSensitive(key) != tt.sensitive {
+				t.Errorf("GetSecret(%s): %s sensitive = %v, want %v", tt.path, key, got.IsSensitive(key), tt.sensitive)
+			}
+		}
+	}
+
+	// The Secret is tried first, and a missing Secret isn't an error
+	fake.requests = nil
+	if _, err := client.GetSecret("team/settings"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	want := []string{"GET /api/v1/namespaces/team/secrets/settings", "GET /api/v1/namespaces/team/configmaps/settings"}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("requests = %v, want %v", fake.requests, want)
+	}
+
+	for _, path := range []string{"team/missing", "team/secrets/settings", "other/app"} {
+		if _, err := client.GetSecret(path); !store.IsNotFound(err) {
+			t.Errorf("GetSecret(%s) error = %v, want not found", path, err)
+		}
+	}
+	if exists, err := client.SecretExists("team/missing"); err != nil || exists {
+		t.Errorf("SecretExists() = %v, %v, want false", exists, err)
+	}
+}
+
+func TestGetSecretBinaryData(t *testing.T) {
+	fake := newFakeCluster(t)
+	client := fake.client()
+
+	keystore := []byte{0x30, 0x82, 0xff, 0xfe, 0x00}
+	encodedKeystore := base64.StdEncoding.EncodeToString(keystore)
+	fake.add("team", kindSecret, "keystore", map[string]string{"keystore.p12": encodedKeystore}, nil)
+	fake.add("team", kindSecret, "tls", map[string]string{"keystore.p12": encodedKeystore, "password": encode("changeit")}, nil)
+	fake.add("team", kindSecret, "broken", map[string]string{"key": "not base64!"}, nil)
+
+	// The only key of an object is read as a binary secret
+	got, err := client.GetSecret("team/keystore")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if content, err := got.BinaryContent(); !got.Binary || err != nil || string(content) != string(keystore) {
+		t.Errorf("GetSecret() = binary %v, %v, want the keystore bytes", got.Binary, err)
+	}
+
+	// Next to other keys it stays readable as a binary key
+	got, err = client.GetSecret("team/tls")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if got.Binary || got.Data["password"] != "changeit" || got.Data["keystore.p12"] != encodedKeystore {
+		t.Errorf("GetSecret() = %v, want the password and the base64 keystore", got.Data)
+	}
+	if !got.IsBinaryKey("keystore.p12") || got.IsBinaryKey("password") {
+		t.Errorf("GetSecret() binary keys = %v, want only keystore.p12", got.BinaryKeys)
+	}
+	if !got.IsSensitive("keystore.p12") || !got.IsSensitive("password") {
+		t.Errorf("GetSecret() sensitive = %v, want every key of a Secret", got.Sensitive)
+	}
+
+	// Writing it back keeps the bytes
+	got.Data["password"] = "changed"
+	if err := client.WriteSecret("team/tls", got); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	written := fake.objects[kindSecret]["team/tls"].Data
+	if written["keystore.p12"] != encodedKeystore || written["password"] != encode("changed") {
+		t.Errorf("written data = %v, want the same keystore", written)
+	}
+
+	if _, err := client.GetSecret("team/broken"); err == nil {
+		t.Errorf("GetSecret() should fail on data that isn't base64")
+	}
+}
+
+func TestGetConfigMapBinaryData(t *testing.T) {
+	fake := newFakeCluster(t)
+	client := fake.client()
+
+	truststore := []byte{0xca, 0xfe, 0xba, 0xbe}
+	encodedTruststore := base64.StdEncoding.EncodeToString(truststore)
+	fake.add("team", kindConfigMap, "java", map[string]string{"opts": "-Xmx1g"}, map[string]string{"cacerts": encodedTruststore, "motd": encode("hello")})
+
+	got, err := client.GetSecret("team/java")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	want := map[string]interface{}{"opts": "-Xmx1g", "cacerts": encodedTruststore, "motd": "hello"}
+	if !reflect.DeepEqual(got.Data, want) {
+		t.Errorf("GetSecret() = %v, want %v", got.Data, want)
+	}
+	if !got.IsBinaryKey("cacerts") || got.IsBinaryKey("motd") {
+		t.Errorf("GetSecret() binary keys = %v, want only cacerts", got.BinaryKeys)
+	}
+	if got.IsSensitive("opts") || got.IsSensitive("cacerts") {
+		t.Errorf("GetSecret() sensitive = %v, want none for a ConfigMap", got.Sensitive)
+	}
+
+	// Binary keys go back to binaryData, text to data
+	if err := client.WriteSecret("team/java", got); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	obj := fake.objects[kindConfigMap]["team/java"]
+	wantData := map[string]string{"opts": "-Xmx1g", "motd": "hello"}
+	wantBinary := map[string]string{"cacerts": encodedTruststore}
+	if !reflect.DeepEqual(obj.Data, wantData) || !reflect.DeepEqual(obj.BinaryData, wantBinary) {
+		t.Errorf("written = data %v, binaryData %v, want %v, %v", obj.Data, obj.BinaryData, wantData, wantBinary)
+	}
+}
+
+func TestWriteSecretRemovesGoneKeys(t *testing.T) {
+	fake := newFakeCluster(t)
+	client := fake.client()
+
+	fake.add("team", kindSecret, "db", map[string]string{"username": encode("app"), "old": encode("stale")}, nil)
+
+	secret := store.NewSecret(map[string]interface{}{"username": "app2", "password": "hunter2"})
+	if err := client.WriteSecret("team/db", secret); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	want := map[string]string{"username": encode("app2"), "password": encode("hunter2")}
+	if got := fake.objects[kindSecret]["team/db"].Data; !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+	if last := fake.requests[len(fake.requests)-1]; last != "PATCH /api/v1/namespaces/team/secrets/db" {
+		t.Errorf("last request = %s, want a patch of the Secret", last)
+	}
+}
+
+func TestWriteSecretCreatesSecret(t *testing.T) {
+	fake := newFakeCluster(t)
+	client := fake.client()
+
+	secret := store.NewSecret(map[string]interface{}{"token": "abc"})
+	if err := client.WriteSecret("team/api", secret); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	obj, ok := fake.objects[kindSecret]["team/api"]
+	if !ok {
+		t.Fatalf("WriteSecret() didn't create a Secret, objects = %v", fake.objects)
+	}
+	if obj.Kind != "Secret" || obj.Type != "Opaque" || obj.Data["token"] != encode("abc") {
+		t.Errorf("created = %+v, want an Opaque Secret with the base64 token", obj)
+	}
+
+	// A ConfigMap is only created when the path asks for one
+	if err := client.WriteSecret("team/configmaps/settings", store.NewSecret(map[string]interface{}{"port": "8080"})); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if obj := fake.objects[kindConfigMap]["team/settings"]; obj.Kind != "ConfigMap" || obj.Data["port"] != "8080" {
+		t.Errorf("created = %+v, want a ConfigMap with the plain port", obj)
+	}
+}
+
+func TestListSecretsFollowsContinue(t *testing.T) {
+	fake := newFakeCluster(t)
+	client := fake.client()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		fake.add("team", kindSecret, name, nil, nil)
+	}
+	fake.add("team", kindConfigMap, "a", nil, nil)
+	fake.add("team", kindConfigMap, "settings", nil, nil)
+	fake.add("other", kindSecret, "x", nil, nil)
+
+	got, err := client.ListSecrets("team/secrets")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	want := []string{"team/secrets/a", "team/secrets/b", "team/secrets/c", "team/secrets/d", "team/secrets/e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSecrets(team/secrets) = %v, want %v", got, want)
+	}
+	if wantLists := []string{"", "2", "4"}; !reflect.DeepEqual(fake.lists, wantLists) {
+		t.Errorf("continue tokens = %v, want %v", fake.lists, wantLists)
+	}
+
+	// Both kinds are listed once for a namespace
+	got, err = client.ListSecrets("/team/")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	want = []string{"team/a", "team/b", "team/c", "team/d", "team/e", "team/settings"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSecrets(team) = %v, want %v", got, want)
+	}
+
+	got, err = client.ListSecrets("")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if want := []string{"other/", "team/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSecrets() = %v, want %v", got, want)
+	}
+
+	if _, err := client.ListSecrets("team/pods"); err == nil {
+		t.Errorf("ListSecrets(team/pods) should fail")
+	}
+}
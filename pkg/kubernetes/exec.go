@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Exec credential API versions understood, as set by cloud CLIs such as
+// aws eks get-token, gke-gcloud-auth-plugin and kubelogin
+const (
+	execAPIVersionV1      = "client.authentication.k8s.io/v1"
+	execAPIVersionV1beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// execConfig is the exec section of a kubeconfig user
+type execConfig struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args"`
+	Env        []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+	InstallHint string `yaml:"installHint"`
+}
+
+// execCredential is the ExecCredential object exchanged with the plugin
+type execCredential struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Interactive bool `json:"interactive"`
+	} `json:"spec"`
+	Status *execCredentialStatus `json:"status,omitempty"`
+}
+
+// execCredentialStatus holds the credentials returned by the plugin.
+// Certificate and key are PEM-encoded.
+type execCredentialStatus struct {
+	Token                 string `json:"token,omitempty"`
+	ClientCertificateData string `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string `json:"clientKeyData,omitempty"`
+}
+
+// runExecCredential runs a kubeconfig exec plugin and returns the credentials
+// it prints. The plugin runs non-interactively since stdin may be in use.
+func runExecCredential(config *execConfig, dir string) (*execCredentialStatus, error) {
+	if config.APIVersion != execAPIVersionV1 && config.APIVersion != execAPIVersionV1beta1 {
+		return nil, fmt.Errorf("unsupported exec credential apiVersion %q, use %s or %s",
+			config.APIVersion, execAPIVersionV1, execAPIVersionV1beta1)
+	}
+	if config.Command == "" {
+		return nil, fmt.Errorf("exec credential plugin has no command")
+	}
+
+	// A relative command with a directory is relative to the kubeconfig, like in kubectl
+	command := config.Command
+	if strings.ContainsRune(command, filepath.Separator) && !filepath.IsAbs(command) {
+		command = filepath.Join(dir, command)
+	}
+
+	request := execCredential{APIVersion: config.APIVersion, Kind: "ExecCredential"}
+	info, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exec credential request: %w", err)
+	}
+
+	cmd := exec.Command(command, config.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(info))
+	for _, env := range config.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	cmd.Stderr = os.Stderr
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) && config.InstallHint != "" {
+			return nil, fmt.Errorf("exec credential plugin %s not found: %s", config.Command, strings.TrimSpace(config.InstallHint))
+		}
+		return nil, fmt.Errorf("exec credential plugin %s failed: %w", config.Command, err)
+	}
+
+	var response execCredential
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse output of exec credential plugin %s: %w", config.Command, err)
+	}
+	if response.Kind != "ExecCredential" || response.APIVersion != config.APIVersion {
+		return nil, fmt.Errorf("exec credential plugin %s returned %s %s, expected ExecCredential %s",
+			config.Command, response.APIVersion, response.Kind, config.APIVersion)
+	}
+	if response.Status == nil || (response.Status.Token == "" && response.Status.ClientCertificateData == "") {
+		return nil, fmt.Errorf("exec credential plugin %s returned no credentials", config.Command)
+	}
+	if (response.Status.ClientCertificateData == "") != (response.Status.ClientKeyData == "") {
+		return nil, fmt.Errorf("exec credential plugin %s returned a client certificate without its key", config.Command)
+	}
+
+	return response.Status, nil
+}
@@ -0,0 +1,330 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// inClusterTokenFile and inClusterCAFile are mounted into every pod with a service account
+	inClusterTokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAFile        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// kubeconfig is the subset of a kubeconfig file needed to reach the API server
+type kubeconfig struct {
+	CurrentContext string        `yaml:"current-context"`
+	Clusters       []kubeCluster `yaml:"clusters"`
+	Users          []kubeUser    `yaml:"users"`
+	Contexts       []kubeContext `yaml:"contexts"`
+}
+
+// kubeCluster is a named cluster entry of a kubeconfig
+type kubeCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthority     string `yaml:"certificate-authority"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		TLSServerName            string `yaml:"tls-server-name"`
+	} `yaml:"cluster"`
+	dir string // directory of the file defining it, for relative paths
+}
+
+// kubeUser is a named user entry of a kubeconfig
+type kubeUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		Token                 string      `yaml:"token"`
+		TokenFile             string      `yaml:"tokenFile"`
+		ClientCertificate     string      `yaml:"client-certificate"`
+		ClientCertificateData string      `yaml:"client-certificate-data"`
+		ClientKey             string      `yaml:"client-key"`
+		ClientKeyData         string      `yaml:"client-key-data"`
+		Exec                  *execConfig `yaml:"exec"`
+	} `yaml:"user"`
+	dir string
+}
+
+// kubeContext is a named context entry of a kubeconfig
+type kubeContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		User      string `yaml:"user"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"context"`
+}
+
+// connection holds what is needed to send authenticated requests to the API server
+type connection struct {
+	server     string
+	namespace  string
+	token      string
+	httpClient *http.Client
+}
+
+// defaultKubeconfigPaths returns the files listed in $KUBECONFIG, or ~/.kube/config
+func defaultKubeconfigPaths() []string {
+	if list := os.Getenv("KUBECONFIG"); list != "" {
+		var paths []string
+		for _, path := range filepath.SplitList(list) {
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
+
+// readKubeconfigs merges kubeconfig files the way kubectl does: the first
+// file to set current-context wins, and so does the first entry of each name.
+// Files from $KUBECONFIG that don't exist are skipped, like kubectl does.
+func readKubeconfigs(paths []string, skipMissing bool) (*kubeconfig, error) {
+	merged := &kubeconfig{}
+	clusters := make(map[string]bool)
+	users := make(map[string]bool)
+	contexts := make(map[string]bool)
+	read := 0
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if skipMissing && os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read kubeconfig %s: %w", path, err)
+		}
+		read++
+
+		var cfg kubeconfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+		}
+
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = cfg.CurrentContext
+		}
+
+		// Relative file references are resolved against the directory of their file
+		dir := filepath.Dir(path)
+		for _, cluster := range cfg.Clusters {
+			if !clusters[cluster.Name] {
+				clusters[cluster.Name] = true
+				cluster.dir = dir
+				merged.Clusters = append(merged.Clusters, cluster)
+			}
+		}
+		for _, user := range cfg.Users {
+			if !users[user.Name] {
+				users[user.Name] = true
+				user.dir = dir
+				merged.Users = append(merged.Users, user)
+			}
+		}
+		for _, context := range cfg.Contexts {
+			if !contexts[context.Name] {
+				contexts[context.Name] = true
+				merged.Contexts = append(merged.Contexts, context)
+			}
+		}
+	}
+
+	if read == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// loadConnection resolves the API server, credentials and default namespace.
+// Without a kubeconfig file, the in-cluster service account is used when available.
+func loadConnection(kubeconfigPath, contextName string) (*connection, error) {
+	var cfg *kubeconfig
+	var err error
+	if kubeconfigPath != "" {
+		cfg, err = readKubeconfigs([]string{kubeconfigPath}, false)
+	} else {
+		paths := defaultKubeconfigPaths()
+		cfg, err = readKubeconfigs(paths, true)
+		if err == nil && cfg == nil {
+			if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+				return loadInClusterConnection()
+			}
+			return nil, fmt.Errorf("no kubeconfig found in %s", strings.Join(paths, ", "))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if contextName == "" {
+		contextName = cfg.CurrentContext
+	}
+	if contextName == "" {
+		return nil, fmt.Errorf("no context specified and kubeconfig has no current-context")
+	}
+
+	// Resolve the context and the cluster and user it refers to
+	var clusterName, userName, namespace string
+	found := false
+	for _, c := range cfg.Contexts {
+		if c.Name == contextName {
+			clusterName, userName, namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %s not found in kubeconfig", contextName)
+	}
+
+	conn := &connection{namespace: namespace}
+	tlsConfig := &tls.Config{}
+
+	found = false
+	for _, c := range cfg.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		conn.server = strings.TrimRight(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = c.Cluster.TLSServerName
+
+		caData, err := readDataOrFile(c.Cluster.CertificateAuthorityData, resolvePath(c.dir, c.Cluster.CertificateAuthority))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cluster CA: %w", err)
+		}
+		if len(caData) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caData) {
+				return nil, fmt.Errorf("no valid certificates found in cluster CA for %s", clusterName)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster %s not found in kubeconfig", clusterName)
+	}
+
+	for _, u := range cfg.Users {
+		if u.Name != userName {
+			continue
+		}
+
+		conn.token = u.User.Token
+		if conn.token == "" && u.User.TokenFile != "" {
+			token, err := os.ReadFile(resolvePath(u.dir, u.User.TokenFile))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read token file: %w", err)
+			}
+			conn.token = strings.TrimSpace(string(token))
+		}
+
+		certData, err := readDataOrFile(u.User.ClientCertificateData, resolvePath(u.dir, u.User.ClientCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		keyData, err := readDataOrFile(u.User.ClientKeyData, resolvePath(u.dir, u.User.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key: %w", err)
+		}
+
+		// An exec plugin provides whatever credentials the entry doesn't set itself
+		if u.User.Exec != nil {
+			credential, err := runExecCredential(u.User.Exec, u.dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get credentials for user %s: %w", userName, err)
+			}
+			if conn.token == "" {
+				conn.token = credential.Token
+			}
+			if len(certData) == 0 && len(keyData) == 0 {
+				certData, keyData = []byte(credential.ClientCertificateData), []byte(credential.ClientKeyData)
+			}
+		}
+
+		if len(certData) > 0 && len(keyData) > 0 {
+			cert, err := tls.X509KeyPair(certData, keyData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		break
+	}
+
+	conn.httpClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+
+	return conn, nil
+}
+
+// resolvePath resolves a file reference relative to the kubeconfig's directory
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// loadInClusterConnection uses the service account mounted into the pod
+func loadInClusterConnection() (*connection, error) {
+	token, err := os.ReadFile(inClusterTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	tlsConfig := &tls.Config{}
+	if caData, err := os.ReadFile(inClusterCAFile); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(caData)
+		tlsConfig.RootCAs = pool
+	}
+
+	conn := &connection{
+		server: "https://" + os.Getenv("KUBERNETES_SERVICE_HOST") + ":" + os.Getenv("KUBERNETES_SERVICE_PORT"),
+		token:  strings.TrimSpace(string(token)),
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+
+	if namespace, err := os.ReadFile(inClusterNamespaceFile); err == nil {
+		conn.namespace = strings.TrimSpace(string(namespace))
+	}
+
+	return conn, nil
+}
+
+// readDataOrFile returns base64-decoded inline data, or the contents of the file
+func readDataOrFile(data, path string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
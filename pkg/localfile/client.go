@@ -0,0 +1,317 @@
+package localfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported file formats
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+)
+
+// Client reads and writes plain .env, JSON and YAML files. A secret path is
+// a file path, relative to the configured directory unless it is absolute.
+type Client struct {
+	directory string
+	format    string
+}
+
+// NewClient creates a client for the local files of the environment
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	switch envConfig.Format {
+	case "", FormatDotenv, FormatJSON, FormatYAML:
+	default:
+		return nil, fmt.Errorf("unsupported local file format %s, must be %s, %s or %s", envConfig.Format, FormatDotenv, FormatJSON, FormatYAML)
+	}
+
+	directory := envConfig.Directory
+	if directory == "" {
+		directory = "."
+	}
+
+	return &Client{
+		directory: directory,
+		format:    envConfig.Format,
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreLocalFile
+}
+
+// filePath resolves a secret path to a file on disk
+func (c *Client) filePath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	return filepath.Join(c.directory, filepath.FromSlash(path)), nil
+}
+
+// detectFormat returns the configured format, or the one implied by the file name
+func (c *Client) detectFormat(file string) (string, error) {
+	if c.format != "" {
+		return c.format, nil
+	}
+
+	base := strings.ToLower(filepath.Base(file))
+	switch filepath.Ext(base) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".env":
+		return FormatDotenv, nil
+	}
+
+	// Covers .env.local, .env.production and the like
+	if strings.HasPrefix(base, ".env") {
+		return FormatDotenv, nil
+	}
+
+	return "", fmt.Errorf("cannot tell the format of %s from its name, set 'format' in the environment config", file)
+}
+
+// GetSecret reads the file at path. Every top-level key is one key of the secret.
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	file, err := c.filePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := c.detectFormat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	data := make(map[string]interface{})
+	switch format {
+	case FormatDotenv:
+		entries, err := parseDotenv(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for _, entry := range entries {
+			data[entry.key] = entry.value
+		}
+	case FormatJSON:
+		if err := json.Unmarshal(content, &data); err != nil {
+			return nil, fmt.Errorf("failed to parse %s, expected a JSON object: %w", file, err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(content, &data); err != nil {
+			return nil, fmt.Errorf("failed to parse %s, expected a YAML mapping: %w", file, err)
+		}
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+	}
+
+	return store.NewSecret(data), nil
+}
+
+// WriteSecret writes the secret to the file at path, creating it if needed.
+// .env files are updated in place so their comments and ordering survive.
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	file, err := c.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	format, err := c.detectFormat(file)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	switch format {
+	case FormatDotenv:
+		existing, err := os.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		values := make(map[string]string, len(secret.Data))
+		for key, value := range secret.Data {
+			values[key] = store.StringValue(value)
+		}
+
+		updated, err := updateDotenv(string(existing), values)
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", file, err)
+		}
+		content = []byte(updated)
+	case FormatJSON:
+		content, err = json.MarshalIndent(secret.Data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal secret: %w", err)
+		}
+		content = append(content, '\n')
+	case FormatYAML:
+		content, err = yaml.Marshal(secret.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal secret: %w", err)
+		}
+	}
+
+	return writeFile(file, content)
+}
+
+// writeFile atomically replaces file, keeping the mode of an existing file.
+// New files are only readable by their owner since they usually hold secrets.
+func writeFile(file string, content []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set mode of %s: %w", file, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// ListSecrets lists the files of a known format and the subdirectories under prefix
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	dir := c.directory
+	if prefix != "" {
+		var err error
+		if dir, err = c.filePath(prefix); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	base := strings.TrimSuffix(prefix, "/")
+	if base != "" {
+		base += "/"
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, base+entry.Name()+"/")
+			continue
+		}
+
+		if _, err := c.detectFormat(entry.Name()); err == nil {
+			names = append(names, base+entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// DeleteSecret removes the file at path
+func (c *Client) DeleteSecret(path string) error {
+	file, err := c.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return fmt.Errorf("failed to delete %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// SecretExists checks whether the file at path exists
+func (c *Client) SecretExists(path string) (bool, error) {
+	file, err := c.filePath(path)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check %s: %w", file, err)
+	}
+
+	return true, nil
+}
+
+// GetMetadata returns the modification time and format of the file.
+// Plain files carry no version.
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	file, err := c.filePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to check %s: %w", file, err)
+	}
+
+	labels := map[string]string{"size": fmt.Sprintf("%d", info.Size())}
+	if format, err := c.detectFormat(file); err == nil {
+		labels["format"] = format
+	}
+
+	return &store.Metadata{
+		Path:        path,
+		UpdatedTime: info.ModTime(),
+		Labels:      labels,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package localfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	values := map[string]interface{}{
+		"DB_HOST":     "localhost",
+		"DB_PASSWORD": `p@ss "word" #1`,
+		"CERT":        "-----BEGIN-----\nabc\n-----END-----",
+		"PATH_LIKE":   `C:\tmp\$HOME`,
+		"EMPTY":       "",
+	}
+
+	for _, file := range []string{".env", "config.json", "config.yaml"} {
+		client, err := NewClient(&config.EnvironmentConfig{Directory: t.TempDir()})
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+
+		if err := client.WriteSecret(file, store.NewSecret(values)); err != nil {
+			t.Fatalf("WriteSecret(%s) error = %v", file, err)
+		}
+		first, err := client.GetSecret(file)
+		if err != nil {
+			t.Fatalf("GetSecret(%s) error = %v", file, err)
+		}
+		if !reflect.DeepEqual(first.Data, values) {
+			t.Errorf("GetSecret(%s) = %q, want %q", file, first.Data, values)
+		}
+
+		// Writing what was read leaves the file as it was
+		written, err := os.ReadFile(filepath.Join(client.directory, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.WriteSecret(file, first); err != nil {
+			t.Fatalf("WriteSecret(%s) error = %v", file, err)
+		}
+		rewritten, err := os.ReadFile(filepath.Join(client.directory, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rewritten) != string(written) {
+			t.Errorf("rewriting %s changed it from\n%s\nto\n%s", file, written, rewritten)
+		}
+
+		info, err := os.Stat(filepath.Join(client.directory, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want 0600", file, info.Mode().Perm())
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	client := &Client{}
+
+	tests := []struct {
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{file: "config.json", want: FormatJSON},
+		{file: "config.YML", want: FormatYAML},
+		{file: "app/config.yaml", want: FormatYAML},
+		{file: ".env", want: FormatDotenv},
+		{file: "prod.env", want: FormatDotenv},
+		{file: ".env.local", want: FormatDotenv},
+		{file: "config.toml", wantErr: true},
+		{file: "secrets", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := client.detectFormat(tt.file)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("detectFormat(%q) = %q, %v, want %q", tt.file, got, err, tt.want)
+		}
+	}
+
+	// A configured format wins over the file name
+	if got, _ := (&Client{format: FormatJSON}).detectFormat("secrets.env"); got != FormatJSON {
+		t.Errorf("detectFormat() = %q, want the configured format", got)
+	}
+}
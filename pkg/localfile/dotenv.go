@@ -0,0 +1,190 @@
+package localfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dotenvEntry is one KEY=VALUE assignment and the lines it spans
+type dotenvEntry struct {
+	key   string
+	value string
+	first int
+	last  int
+}
+
+// parseDotenv parses a .env file. It understands comments, blank lines, an
+// optional "export " prefix, single-quoted literals and double-quoted values
+// with escapes, both of which may span several lines. Values are not expanded.
+func parseDotenv(content string) ([]dotenvEntry, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var entries []dotenvEntry
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		key, rest, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", i+1)
+		}
+
+		entry := dotenvEntry{key: key, first: i, last: i}
+		rest = strings.TrimLeft(rest, " \t")
+
+		switch {
+		case strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, `'`):
+			quote := rest[0]
+			value, last, err := readQuoted(lines, i, rest[1:], quote)
+			if err != nil {
+				return nil, err
+			}
+			entry.value = value
+			entry.last = last
+			i = last
+		default:
+			// An unquoted value ends at an inline comment
+			if idx := strings.Index(rest, " #"); idx >= 0 {
+				rest = rest[:idx]
+			}
+			entry.value = strings.TrimSpace(rest)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// readQuoted reads a quoted value starting after its opening quote on line
+// start, continuing on the following lines until the closing quote
+func readQuoted(lines []string, start int, rest string, quote byte) (string, int, error) {
+	var value strings.Builder
+
+	for i := start; i < len(lines); i++ {
+		if i > start {
+			value.WriteByte('\n')
+			rest = lines[i]
+		}
+
+		for j := 0; j < len(rest); j++ {
+			c := rest[j]
+			switch {
+			case c == quote:
+				return value.String(), i, nil
+			case c == '\\' && quote == '"' && j+1 < len(rest):
+				j++
+				switch rest[j] {
+				case 'n':
+					value.WriteByte('\n')
+				case 'r':
+					value.WriteByte('\r')
+				case 't':
+					value.WriteByte('\t')
+				default:
+					value.WriteByte(rest[j])
+				}
+			default:
+				value.WriteByte(c)
+			}
+		}
+	}
+
+	return "", 0, fmt.Errorf("line %d: unterminated quoted value", start+1)
+}
+
+// formatDotenvValue renders a value, quoting it only when needed
+func formatDotenvValue(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	if !strings.ContainsAny(value, " \t\r\n\"'#\\=$`") {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// updateDotenv rewrites a .env file to hold exactly data. Assignments of kept
+// keys are updated in place, removed keys are dropped, new keys are appended
+// in sorted order, and comments and blank lines are left untouched.
+func updateDotenv(content string, data map[string]string) (string, error) {
+	entries, err := parseDotenv(content)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	// Map each line to the entry starting on it, and mark lines that continue an entry
+	starts := make(map[int]dotenvEntry)
+	continued := make(map[int]bool)
+	for _, entry := range entries {
+		starts[entry.first] = entry
+		for i := entry.first + 1; i <= entry.last; i++ {
+			continued[i] = true
+		}
+	}
+
+	written := make(map[string]bool)
+	var out []string
+	for i, line := range lines {
+		if continued[i] {
+			continue
+		}
+
+		entry, ok := starts[i]
+		if !ok {
+			out = append(out, line)
+			continue
+		}
+
+		value, keep := data[entry.key]
+		if !keep || written[entry.key] {
+			continue
+		}
+
+		written[entry.key] = true
+
+		// Unchanged values keep their original quoting and inline comments
+		if value == entry.value {
+			out = append(out, lines[entry.first:entry.last+1]...)
+			continue
+		}
+
+		prefix := ""
+		if strings.HasPrefix(strings.TrimSpace(line), "export ") {
+			prefix = "export "
+		}
+		out = append(out, prefix+entry.key+"="+formatDotenvValue(value))
+	}
+
+	// Drop the trailing empty line so new keys follow the last assignment
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if !written[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		out = append(out, key+"="+formatDotenvValue(data[key]))
+	}
+
+	return strings.Join(out, "\n") + "\n", nil
+}
@@ -0,0 +1,104 @@
+package localfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{name: "plain", content: "A=1\nB=two words\n", want: map[string]string{"A": "1", "B": "two words"}},
+		{name: "comments and blank lines", content: "# header\n\nA=1\n  # indented\nB=2", want: map[string]string{"A": "1", "B": "2"}},
+		{name: "inline comment", content: "A=value # note\nB=a#b\n", want: map[string]string{"A": "value", "B": "a#b"}},
+		{name: "export prefix", content: "export A=1\nexport  B = 2\n", want: map[string]string{"A": "1", "B": "2"}},
+		{name: "spaces around equals", content: "A = padded  \n", want: map[string]string{"A": "padded"}},
+		{name: "empty value", content: "A=\nB=\"\"\n", want: map[string]string{"A": "", "B": ""}},
+		{name: "double quotes", content: `A="quoted # not a comment" # comment` + "\n", want: map[string]string{"A": "quoted # not a comment"}},
+		{name: "escapes", content: `A="line1\nline2\ttab \"q\" back\\slash"` + "\n", want: map[string]string{"A": "line1\nline2\ttab \"q\" back\\slash"}},
+		{name: "single quotes are literal", content: `A='no\nescape "here"'` + "\n", want: map[string]string{"A": `no\nescape "here"`}},
+		{name: "multi-line value", content: "A=\"first\nsecond\"\nB=after\n", want: map[string]string{"A": "first\nsecond", "B": "after"}},
+		{name: "multi-line single quotes", content: "KEY='-----BEGIN-----\nabc\n-----END-----'\n", want: map[string]string{"KEY": "-----BEGIN-----\nabc\n-----END-----"}},
+		{name: "windows line endings", content: "A=1\r\nB=2\r\n", want: map[string]string{"A": "1", "B": "2"}},
+		{name: "no expansion", content: "A=$HOME\nB=\"${A}\"\n", want: map[string]string{"A": "$HOME", "B": "${A}"}},
+		{name: "equals in value", content: "A=b=c\n", want: map[string]string{"A": "b=c"}},
+	}
+
+	for _, tt := range tests {
+		entries, err := parseDotenv(tt.content)
+		if err != nil {
+			t.Errorf("%s: parseDotenv() error = %v", tt.name, err)
+			continue
+		}
+		got := make(map[string]string)
+		for _, entry := range entries {
+			got[entry.key] = entry.value
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseDotenv() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	for _, content := range []string{"JUSTAKEY\n", "=value\n", "TWO WORDS=1\n", "A=\"unterminated\nB=2\n", "A='open\n"} {
+		if _, err := parseDotenv(content); err == nil {
+			t.Errorf("parseDotenv(%q) should fail", content)
+		}
+	}
+}
+
+func TestFormatDotenvValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "simple", want: "simple"},
+		{value: "two words", want: `"two words"`},
+		{value: "a#b", want: `"a#b"`},
+		{value: "line1\nline2", want: `"line1\nline2"`},
+		{value: `say "hi"`, want: `"say \"hi\""`},
+		{value: `back\slash`, want: `"back\\slash"`},
+		{value: "$HOME", want: `"$HOME"`},
+	}
+
+	for _, tt := range tests {
+		if got := formatDotenvValue(tt.value); got != tt.want {
+			t.Errorf("formatDotenvValue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+
+		// Whatever is written reads back as the same value
+		entries, err := parseDotenv("KEY=" + formatDotenvValue(tt.value) + "\n")
+		if err != nil || len(entries) != 1 || entries[0].value != tt.value {
+			t.Errorf("parseDotenv(formatDotenvValue(%q)) = %v, %v", tt.value, entries, err)
+		}
+	}
+}
+
+func TestUpdateDotenv(t *testing.T) {
+	content := "# database\nexport DB_HOST=localhost # dev box\nDB_PASSWORD=\"old\"\n\n# removed below\nOLD=1\nCERT=\"a\nb\"\n"
+	data := map[string]string{
+		"DB_HOST":     "localhost",
+		"DB_PASSWORD": "new secret",
+		"CERT":        "c",
+		"API_KEY":     "k",
+		"ALPHA":       "z",
+	}
+
+	got, err := updateDotenv(content, data)
+	if err != nil {
+		t.Fatalf("updateDotenv() error = %v", err)
+	}
+	want := "# database\nexport DB_HOST=localhost # dev box\nDB_PASSWORD=\"new secret\"\n\n# removed below\nCERT=c\nALPHA=z\nAPI_KEY=k\n"
+	if got != want {
+		t.Errorf("updateDotenv() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got, err := updateDotenv("", map[string]string{"B": "2", "A": "1"}); err != nil || got != "A=1\nB=2\n" {
+		t.Errorf("updateDotenv() of a new file = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,272 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// maxResponseSize bounds a single response line from a plugin
+const maxResponseSize = 16 * 1024 * 1024
+
+// Client drives an external store plugin. The plugin is started on first use
+// and exchanges one JSON request and one JSON response per line over its
+// stdin and stdout; its stderr is passed through for logging. The plugin
+// should exit when its stdin is closed.
+type Client struct {
+	path   string
+	args   []string
+	config map[string]string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+	nextID int
+}
+
+// NewClient creates a client for the plugin named in the environment config
+func NewClient(envConfig *config.EnvironmentConfig) (*Client, error) {
+	if envConfig.Plugin == "" {
+		return nil, fmt.Errorf("plugin executable is required")
+	}
+
+	path, err := exec.LookPath(envConfig.Plugin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find plugin %s: %w", envConfig.Plugin, err)
+	}
+
+	return &Client{
+		path:   path,
+		args:   envConfig.PluginArgs,
+		config: envConfig.PluginConfig,
+	}, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StorePlugin
+}
+
+// start launches the plugin and sends the init request. Must be called with mu held.
+func (c *Client) start() error {
+	cmd := exec.Command(c.path, c.args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open plugin stdin: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open plugin stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", c.path, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxResponseSize)
+
+	c.cmd = cmd
+	c.stdin = stdin
+	c.stdout = scanner
+
+	_, err = c.roundTrip(&Request{
+		Method:          MethodInit,
+		ProtocolVersion: ProtocolVersion,
+		Config:          c.config,
+	})
+	if err != nil {
+		c.stop()
+		return fmt.Errorf("plugin %s failed to initialize: %w", c.path, err)
+	}
+
+	return nil
+}
+
+// stop closes the plugin's stdin and waits for it to exit. Must be called with mu held.
+func (c *Client) stop() error {
+	if c.cmd == nil {
+		return nil
+	}
+
+	c.stdin.Close()
+	err := c.cmd.Wait()
+	c.cmd = nil
+	return err
+}
+
+// Close stops the plugin process if it was started
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.stop(); err != nil {
+		return fmt.Errorf("plugin exited with error: %w", err)
+	}
+	return nil
+}
+
+// roundTrip writes a request and reads its response. Must be called with mu held.
+func (c *Client) roundTrip(request *Request) (*Response, error) {
+	c.nextID++
+	request.ID = c.nextID
+
+	line, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plugin request: %w", err)
+	}
+
+	// A plugin that can't be talked to anymore is reaped, so the next call starts it again
+	if _, err := c.stdin.Write(append(line, '\n')); err != nil {
+		c.stop()
+		return nil, fmt.Errorf("failed to send %s request to plugin: %w", request.Method, err)
+	}
+
+	if !c.stdout.Scan() {
+		err := c.stdout.Err()
+		c.stop()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plugin response: %w", err)
+		}
+		return nil, fmt.Errorf("plugin closed its output during %s request", request.Method)
+	}
+
+	var response Response
+	if err := json.Unmarshal(c.stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin response: %w", err)
+	}
+
+	if response.ID != request.ID {
+		return nil, fmt.Errorf("plugin answered request %d while %d was expected", response.ID, request.ID)
+	}
+
+	if response.Error != nil {
+		if response.Error.Code == ErrorCodeNotFound {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, request.Path)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %s", request.Method, response.Error.Message)
+	}
+
+	return &response, nil
+}
+
+// call sends one request, starting the plugin first if needed
+func (c *Client) call(request *Request) (*Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cmd == nil {
+		if err := c.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.roundTrip(request)
+}
+
+// GetSecret asks the plugin for the secret at path
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	response, err := c.call(&Request{Method: MethodGet, Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Secret == nil {
+		return nil, fmt.Errorf("plugin returned no secret for %s", path)
+	}
+
+	return fromWire(response.Secret), nil
+}
+
+// SupportsBinary reports that binary secrets are passed to the plugin, base64-encoded
+func (c *Client) SupportsBinary() bool {
+	return true
+}
+
+// WriteSecret asks the plugin to create or replace the secret at path
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	_, err := c.call(&Request{Method: MethodPut, Path: path, Secret: toWire(secret)})
+	return err
+}
+
+// ListSecrets asks the plugin for the secrets and "/"-suffixed folders under prefix
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	response, err := c.call(&Request{Method: MethodList, Path: prefix})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Paths == nil {
+		return []string{}, nil
+	}
+	return response.Paths, nil
+}
+
+// DeleteSecret asks the plugin to delete the secret at path
+func (c *Client) DeleteSecret(path string) error {
+	_, err := c.call(&Request{Method: MethodDelete, Path: path})
+	return err
+}
+
+// SecretExists checks for the secret through the metadata method
+func (c *Client) SecretExists(path string) (bool, error) {
+	_, err := c.GetMetadata(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetMetadata asks the plugin for the metadata of the secret at path
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	response, err := c.call(&Request{Method: MethodMetadata, Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &store.Metadata{Path: path}
+	if response.Metadata != nil {
+		metadata.Version = response.Metadata.Version
+		metadata.CreatedTime = response.Metadata.CreatedTime
+		metadata.UpdatedTime = response.Metadata.UpdatedTime
+		metadata.Labels = response.Metadata.Labels
+	}
+
+	return metadata, nil
+}
+
+// ListVersions asks the plugin for the versions of the secret at path, oldest first.
+// Plugins without version history should answer with an error.
+func (c *Client) ListVersions(path string) ([]store.VersionInfo, error) {
+	response, err := c.call(&Request{Method: MethodVersions, Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]store.VersionInfo, 0, len(response.Versions))
+	for _, v := range response.Versions {
+		versions = append(versions, store.VersionInfo{
+			Version:     v.Version,
+			CreatedTime: v.CreatedTime,
+			Deleted:     v.Deleted,
+			Author:      v.Author,
+		})
+	}
+
+	return versions, nil
+}
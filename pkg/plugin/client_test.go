@@ -0,0 +1,202 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// helperClient returns a client whose plugin is this test binary running
+// TestHelperProcess in the given mode
+func helperClient(t *testing.T, mode string) *Client {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	client := &Client{
+		path:   os.Args[0],
+		args:   []string{"-test.run=TestHelperProcess", "--", mode},
+		config: map[string]string{"region": "eu"},
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestHelperProcess isn't a real test. It is started by the tests below and
+// acts as a plugin keeping its secrets in memory.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	mode := os.Args[len(os.Args)-1]
+
+	secrets := map[string]*Secret{}
+	encoder := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request Request
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			fmt.Fprintf(os.Stderr, "helper: %v\n", err)
+			os.Exit(2)
+		}
+
+		response := Response{ID: request.ID}
+		notFound := &Error{Code: ErrorCodeNotFound, Message: "no secret at " + request.Path}
+		switch request.Method {
+		case MethodInit:
+			if request.ProtocolVersion != ProtocolVersion || request.Config["region"] != "eu" {
+				response.Error = &Error{Message: fmt.Sprintf("unexpected init %+v", request)}
+			}
+		case MethodGet:
+			if request.Path == "crash" {
+				os.Exit(3)
+			}
+			if response.Secret = secrets[request.Path]; response.Secret == nil {
+				response.Error = notFound
+			}
+		case MethodPut:
+			if strings.HasPrefix(request.Path, "readonly/") {
+				response.Error = &Error{Code: "forbidden", Message: "read-only folder"}
+				break
+			}
+			secrets[request.Path] = request.Secret
+		case MethodList:
+			response.Paths = []string{}
+			for path := range secrets {
+				if strings.HasPrefix(path, request.Path) {
+					response.Paths = append(response.Paths, path)
+				}
+			}
+			sort.Strings(response.Paths)
+		case MethodDelete:
+			if secrets[request.Path] == nil {
+				response.Error = notFound
+			}
+			delete(secrets, request.Path)
+		case MethodMetadata:
+			if secrets[request.Path] == nil && request.Path != "pid" {
+				response.Error = notFound
+				break
+			}
+			response.Metadata = &Metadata{Version: "1", Labels: map[string]string{"pid": strconv.Itoa(os.Getpid())}}
+		default:
+			response.Error = &Error{Message: "unknown method " + request.Method}
+		}
+
+		if mode == "wrong-id" && request.Method != MethodInit {
+			response.ID += 100
+		}
+		if err := encoder.Encode(response); err != nil {
+			os.Exit(2)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestPluginMethods(t *testing.T) {
+	client := helperClient(t, "store")
+
+	secret := store.NewSecret(map[string]interface{}{"username": "app", "password": "hunter2"})
+	secret.Sensitive["password"] = true
+	if err := client.WriteSecret("app/dev/db", secret); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if err := client.WriteSecret("app/dev/api", store.NewSecret(map[string]interface{}{"token": "abc"})); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	got, err := client.GetSecret("app/dev/db")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if !got.IsJSON || !reflect.DeepEqual(got.Data, secret.Data) || !got.IsSensitive("password") || got.IsSensitive("username") {
+		t.Errorf("GetSecret() = %v, sensitive %v, want %v with password sensitive", got.Data, got.Sensitive, secret.Data)
+	}
+
+	paths, err := client.ListSecrets("app/")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if want := []string{"app/dev/api", "app/dev/db"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("ListSecrets() = %v, want %v", paths, want)
+	}
+
+	metadata, err := client.GetMetadata("app/dev/db")
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if metadata.Path != "app/dev/db" || metadata.Version != "1" {
+		t.Errorf("GetMetadata() = %+v", metadata)
+	}
+
+	if err := client.DeleteSecret("app/dev/api"); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if exists, err := client.SecretExists("app/dev/api"); err != nil || exists {
+		t.Errorf("SecretExists() after delete = %v, %v, want false", exists, err)
+	}
+
+	// A binary secret keeps its flag over the wire
+	if err := client.WriteSecret("app/keystore", store.NewBinarySecret([]byte{0x00, 0xff})); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	got, err = client.GetSecret("app/keystore")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if content, err := got.BinaryContent(); err != nil || string(content) != "\x00\xff" {
+		t.Errorf("GetSecret() binary = %q, %v, want the written bytes", content, err)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	client := helperClient(t, "store")
+
+	if _, err := client.GetSecret("app/missing"); !store.IsNotFound(err) {
+		t.Errorf("GetSecret() error = %v, want not found", err)
+	}
+	if err := client.DeleteSecret("app/missing"); !store.IsNotFound(err) {
+		t.Errorf("DeleteSecret() error = %v, want not found", err)
+	}
+
+	err := client.WriteSecret("readonly/db", store.NewSecret(map[string]interface{}{"a": "b"}))
+	if err == nil || store.IsNotFound(err) || !strings.Contains(err.Error(), "read-only folder") {
+		t.Errorf("WriteSecret() error = %v, want the plugin's message", err)
+	}
+}
+
+func TestPluginResponseIDMismatch(t *testing.T) {
+	client := helperClient(t, "wrong-id")
+
+	_, err := client.GetSecret("app/db")
+	if err == nil || !strings.Contains(err.Error(), "while 2 was expected") {
+		t.Errorf("GetSecret() error = %v, want an ID mismatch", err)
+	}
+}
+
+func TestPluginRestartsAfterExit(t *testing.T) {
+	client := helperClient(t, "store")
+
+	before, err := client.GetMetadata("pid")
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+
+	// The plugin exits without answering
+	if _, err := client.GetSecret("crash"); err == nil || store.IsNotFound(err) {
+		t.Errorf("GetSecret() error = %v, want the plugin to have closed its output", err)
+	}
+
+	after, err := client.GetMetadata("pid")
+	if err != nil {
+		t.Fatalf("GetMetadata() after the exit error = %v", err)
+	}
+	if after.Labels["pid"] == before.Labels["pid"] {
+		t.Errorf("plugin pid = %s after it exited, want a new process", after.Labels["pid"])
+	}
+}
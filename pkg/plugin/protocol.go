@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/secretz/vault-promoter/pkg/store"
+)
+
+// ProtocolVersion is sent in the init request. Plugins should refuse versions they don't know.
+const ProtocolVersion = 1
+
+// Methods understood by a plugin
+const (
+	MethodInit     = "init"
+	MethodGet      = "get"
+	MethodPut      = "put"
+	MethodList     = "list"
+	MethodDelete   = "delete"
+	MethodMetadata = "metadata"
+	MethodVersions = "versions"
+)
+
+// ErrorCodeNotFound is the error code a plugin returns for a missing secret
+const ErrorCodeNotFound = "not_found"
+
+// Request is one line written to the plugin's stdin
+type Request struct {
+	ID     int               `json:"id"`
+	Method string            `json:"method"`
+	Path   string            `json:"path,omitempty"`
+	Secret *Secret           `json:"secret,omitempty"`
+	Config map[string]string `json:"config,omitempty"`
+
+	// Only sent with init
+	ProtocolVersion int `json:"protocol_version,omitempty"`
+}
+
+// Response is one line read from the plugin's stdout
+type Response struct {
+	ID       int       `json:"id"`
+	Secret   *Secret   `json:"secret,omitempty"`
+	Paths    []string  `json:"paths,omitempty"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+	Versions []Version `json:"versions,omitempty"`
+	Error    *Error    `json:"error,omitempty"`
+}
+
+// Secret is the wire form of store.Secret
+type Secret struct {
+	Data      map[string]interface{} `json:"data"`
+	IsJSON    bool                   `json:"is_json"`
+	Binary    bool                   `json:"binary,omitempty"`
+	Sensitive []string               `json:"sensitive,omitempty"`
+}
+
+// Metadata is the wire form of store.Metadata
+type Metadata struct {
+	Version     string            `json:"version,omitempty"`
+	CreatedTime time.Time         `json:"created_time,omitempty"`
+	UpdatedTime time.Time         `json:"updated_time,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// Version is the wire form of store.VersionInfo
+type Version struct {
+	Version     string    `json:"version"`
+	CreatedTime time.Time `json:"created_time,omitempty"`
+	Deleted     bool      `json:"deleted,omitempty"`
+	Author      string    `json:"author,omitempty"`
+}
+
+// Error is returned by a plugin instead of a result
+type Error struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// toWire converts a secret for a put request
+func toWire(secret *store.Secret) *Secret {
+	wire := &Secret{
+		Data:   secret.Data,
+		IsJSON: secret.IsJSON,
+		Binary: secret.Binary,
+	}
+	for key := range secret.Data {
+		if secret.IsSensitive(key) {
+			wire.Sensitive = append(wire.Sensitive, key)
+		}
+	}
+	return wire
+}
+
+// fromWire converts a secret returned by get
+func fromWire(wire *Secret) *store.Secret {
+	secret := store.NewSecret(wire.Data)
+	secret.IsJSON = wire.IsJSON
+	secret.Binary = wire.Binary
+	for _, key := range wire.Sensitive {
+		secret.Sensitive[key] = true
+	}
+	return secret
+}
@@ -0,0 +1,306 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrSecretNotFound is returned (wrapped) by every store when a path holds no secret
+var ErrSecretNotFound = errors.New("secret not found")
+
+// VersionSeparator separates a path from a pinned version, as in "app/dev/config@5"
+const VersionSeparator = "@"
+
+// SecretStore is implemented by every backend that can hold secrets.
+// Compare, copy and split are written against this interface so that a new
+// backend only has to implement it and be registered in comparison.NewStore.
+type SecretStore interface {
+	// Type returns the store type as written in the config file (e.g. "vault")
+	Type() string
+
+	// GetSecret reads the secret at path
+	GetSecret(path string) (*Secret, error)
+
+	// WriteSecret creates or replaces the secret at path
+	WriteSecret(path string, secret *Secret) error
+
+	// ListSecrets returns the paths found directly under prefix.
+	// Entries ending in "/" are folders that can be listed in turn.
+	ListSecrets(prefix string) ([]string, error)
+
+	// DeleteSecret removes the secret at path
+	DeleteSecret(path string) error
+
+	// SecretExists reports whether a secret is stored at path
+	SecretExists(path string) (bool, error)
+
+	// GetMetadata returns version and timestamp information for the secret at path
+	GetMetadata(path string) (*Metadata, error)
+}
+
+// BinaryStore is implemented by stores that can hold binary secrets,
+// either natively or in the form written by BinaryData
+type BinaryStore interface {
+	SupportsBinary() bool
+}
+
+// VersionedStore is implemented by stores that keep earlier versions of a secret.
+// Every listed version can be read with GetSecret("path@version").
+type VersionedStore interface {
+	// ListVersions returns the versions of the secret at path, oldest first
+	ListVersions(path string) ([]VersionInfo, error)
+}
+
+// Provisioner is implemented by stores that need a container created
+// (for example a Vault KV mount) before secrets can be written to it
+type Provisioner interface {
+	Provision() error
+}
+
+// AccessChecker is implemented by stores that can check the permissions of
+// their credentials up front, so a run fails before it changes anything
+// instead of halfway through
+type AccessChecker interface {
+	// CheckAccess returns an error if path can't be read, or written when write is set
+	CheckAccess(path string, write bool) error
+}
+
+// Secret is the store-agnostic view of a secret's data
+type Secret struct {
+	// Data holds the key/value pairs of the secret
+	Data map[string]interface{}
+
+	// IsJSON is false when the backend holds a single opaque string,
+	// which is then exposed under the "value" key
+	IsJSON bool
+
+	// Binary is true when the secret is raw bytes. The bytes are kept
+	// base64-encoded under the "value" key so they survive as a string.
+	Binary bool
+
+	// Sensitive marks keys the backend itself knows to be secret,
+	// regardless of whether the key name matches the redaction list
+	Sensitive map[string]bool
+
+	// BinaryKeys marks keys of a structured secret whose value is raw bytes,
+	// such as a keystore next to its password. Their value is kept
+	// base64-encoded; stores that can't hold bytes in a key write that text.
+	BinaryKeys map[string]bool
+}
+
+// NewSecret returns a structured secret holding data
+func NewSecret(data map[string]interface{}) *Secret {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	return &Secret{
+		Data:       data,
+		IsJSON:     true,
+		Sensitive:  make(map[string]bool),
+		BinaryKeys: make(map[string]bool),
+	}
+}
+
+// BinaryEncodingKey is stored next to "value" when a binary secret is kept in a
+// store that only holds key/value strings, so it can be read back as bytes
+const BinaryEncodingKey = "_encoding"
+
+// binaryEncoding is the only encoding written under BinaryEncodingKey
+const binaryEncoding = "base64"
+
+// NewBinarySecret returns a secret holding raw bytes
+func NewBinarySecret(content []byte) *Secret {
+	secret := NewSecret(map[string]interface{}{"value": base64.StdEncoding.EncodeToString(content)})
+	secret.IsJSON = false
+	secret.Binary = true
+	return secret
+}
+
+// BinaryContent decodes the bytes of a binary secret
+func (s *Secret) BinaryContent() ([]byte, error) {
+	if !s.Binary {
+		return nil, fmt.Errorf("secret is not binary")
+	}
+
+	encoded, ok := s.Data["value"].(string)
+	if !ok {
+		return nil, fmt.Errorf("binary secret has no base64 value")
+	}
+
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode binary secret: %w", err)
+	}
+	return content, nil
+}
+
+// BinarySummary describes a binary secret by its SHA-256 hash and size,
+// so it can be compared and reported without printing its bytes
+func (s *Secret) BinarySummary() string {
+	content, err := s.BinaryContent()
+	if err != nil {
+		return "(invalid binary content)"
+	}
+
+	return binarySummary(content)
+}
+
+// IsBinaryKey reports whether the value of key is base64-encoded raw bytes
+func (s *Secret) IsBinaryKey(key string) bool {
+	if s == nil || s.BinaryKeys == nil {
+		return false
+	}
+	return s.BinaryKeys[key]
+}
+
+// KeySummary describes the raw bytes of a binary key like BinarySummary
+func (s *Secret) KeySummary(key string) string {
+	encoded, _ := s.Data[key].(string)
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "(invalid binary content)"
+	}
+	return binarySummary(content)
+}
+
+// binarySummary describes content by its SHA-256 hash and size
+func binarySummary(content []byte) string {
+	sum := sha256.Sum256(content)
+	return fmt.Sprintf("binary, sha256:%s, %d bytes", hex.EncodeToString(sum[:]), len(content))
+}
+
+// BinaryData returns the key/value form of a binary secret for stores that
+// only hold strings. SecretFromData turns it back into a binary secret.
+func BinaryData(secret *Secret) map[string]interface{} {
+	return map[string]interface{}{
+		"value":           secret.Data["value"],
+		BinaryEncodingKey: binaryEncoding,
+	}
+}
+
+// SecretFromData returns a structured secret holding data, or the binary
+// secret it encodes when it was written by BinaryData
+func SecretFromData(data map[string]interface{}) *Secret {
+	if len(data) == 2 && data[BinaryEncodingKey] == binaryEncoding {
+		if encoded, ok := data["value"].(string); ok {
+			if content, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				return NewBinarySecret(content)
+			}
+		}
+	}
+	return NewSecret(data)
+}
+
+// IsSensitive reports whether the backend flagged key as secret
+func (s *Secret) IsSensitive(key string) bool {
+	if s == nil || s.Sensitive == nil {
+		return false
+	}
+	return s.Sensitive[key]
+}
+
+// MarkAllSensitive flags every key of the secret as sensitive, for backends
+// where everything stored is a secret
+func (s *Secret) MarkAllSensitive() {
+	for key := range s.Data {
+		s.Sensitive[key] = true
+	}
+}
+
+// MarkOpaqueSensitive flags the value of a binary or plain string secret,
+// whose whole content is the secret. Keys of JSON secrets are left to the
+// key rules of the config, since secret stores often hold configuration too.
+func (s *Secret) MarkOpaqueSensitive() {
+	if s.Binary || !s.IsJSON {
+		s.Sensitive["value"] = true
+	}
+}
+
+// ParseSecretString parses a string payload: a JSON object becomes the keys
+// of the secret, anything else is kept whole under the "value" key
+func ParseSecretString(payload string) *Secret {
+	secret := NewSecret(nil)
+
+	var secretData map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &secretData); err != nil || secretData == nil {
+		// Not a JSON object, return as a single value
+		secret.Data["value"] = payload
+		secret.IsJSON = false
+	} else {
+		secret.Data = secretData
+	}
+
+	return secret
+}
+
+// MarshalSecretString is the reverse of ParseSecretString
+func MarshalSecretString(secret *Secret) (string, error) {
+	if !secret.IsJSON {
+		return StringValue(secret.Data["value"]), nil
+	}
+
+	jsonData, err := json.Marshal(secret.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal secret data: %w", err)
+	}
+	return string(jsonData), nil
+}
+
+// StringValue renders a secret value as a string. Nested objects and lists
+// are written as JSON so they read the same in every store.
+func StringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(v)
+		if err == nil {
+			return string(encoded)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// Metadata describes a secret without exposing its values
+type Metadata struct {
+	Path        string
+	Version     string
+	CreatedTime time.Time
+	UpdatedTime time.Time
+	Labels      map[string]string
+}
+
+// VersionInfo describes one version of a secret
+type VersionInfo struct {
+	Version     string // selector to append to the path after VersionSeparator
+	CreatedTime time.Time
+	Deleted     bool   // the version is kept in the history but can't be read anymore
+	Author      string // who wrote the version, when the store records it
+}
+
+// IsNotFound reports whether err means the secret does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrSecretNotFound)
+}
+
+// versionSelector matches what may follow VersionSeparator: a version number,
+// "latest", a Secrets Manager staging label or a version ID
+var versionSelector = regexp.MustCompile(`^([0-9]+|latest|AWSCURRENT|AWSPREVIOUS|AWSPENDING|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// SplitVersion splits "path@version" into the path and the pinned version.
+// The version is empty when the path doesn't pin one. Anything after the last
+// "@" that isn't a version selector is part of the name, as in "svc@team".
+func SplitVersion(path string) (string, string) {
+	idx := strings.LastIndex(path, VersionSeparator)
+	if idx < 0 || !versionSelector.MatchString(path[idx+len(VersionSeparator):]) {
+		return path, ""
+	}
+	return path[:idx], path[idx+len(VersionSeparator):]
+}
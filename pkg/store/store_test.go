@@ -0,0 +1,33 @@
+package store
+
+import "testing"
+
+func TestSplitVersion(t *testing.T) {
+	tests := []struct {
+		path    string
+		name    string
+		version string
+	}{
+		{path: "app/dev/config", name: "app/dev/config", version: ""},
+		{path: "app/dev/config@12", name: "app/dev/config", version: "12"},
+		{path: "app/dev/config@latest", name: "app/dev/config", version: "latest"},
+		{path: "app/config@AWSPREVIOUS", name: "app/config", version: "AWSPREVIOUS"},
+		{path: "app/config@AWSCURRENT", name: "app/config", version: "AWSCURRENT"},
+		{path: "app/config@AWSPENDING", name: "app/config", version: "AWSPENDING"},
+		{path: "app/config@3f2a1b4c-5d6e-4f70-8a9b-0c1d2e3f4a5b", name: "app/config", version: "3f2a1b4c-5d6e-4f70-8a9b-0c1d2e3f4a5b"},
+		{path: "svc@team", name: "svc@team", version: ""},
+		{path: "app/svc@team", name: "app/svc@team", version: ""},
+		{path: "ops@corp/db", name: "ops@corp/db", version: ""},
+		{path: "app/config@", name: "app/config@", version: ""},
+		{path: "app/config@awscurrent", name: "app/config@awscurrent", version: ""},
+		{path: "svc@team@5", name: "svc@team", version: "5"},
+		{path: "svc@2024@AWSCURRENT", name: "svc@2024", version: "AWSCURRENT"},
+	}
+
+	for _, tt := range tests {
+		name, version := SplitVersion(tt.path)
+		if name != tt.name || version != tt.version {
+			t.Errorf("SplitVersion(%q) = %q, %q, want %q, %q", tt.path, name, version, tt.name, tt.version)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/secretz/vault-promoter/pkg/config"
+)
+
+// TLSConfig builds the TLS settings of an environment. It returns nil when
+// the environment has none, so the system defaults apply.
+func TLSConfig(envConfig *config.EnvironmentConfig) (*tls.Config, error) {
+	if envConfig.CACert == "" && envConfig.CAPath == "" && envConfig.ClientCert == "" && envConfig.TLSServerName == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: envConfig.TLSServerName,
+	}
+
+	if envConfig.CACert != "" || envConfig.CAPath != "" {
+		pool, err := loadCAs(envConfig.CACert, envConfig.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if envConfig.ClientCert != "" {
+		certificate, err := tls.LoadX509KeyPair(envConfig.ClientCert, envConfig.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %s: %w", envConfig.ClientCert, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// loadCAs reads the CA certificates of a PEM file and of every file in a directory.
+// Only these CAs are trusted, not the system ones.
+func loadCAs(caCert, caPath string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	if caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caCert)
+		}
+	}
+
+	if caPath != "" {
+		entries, err := os.ReadDir(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA directory: %w", err)
+		}
+
+		found := false
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			data, err := os.ReadFile(filepath.Join(caPath, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+			}
+			if pool.AppendCertsFromPEM(data) {
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caPath)
+		}
+	}
+
+	return pool, nil
+}
+
+// Configure applies the TLS and proxy settings of an environment to t
+func Configure(t *http.Transport, envConfig *config.EnvironmentConfig) error {
+	tlsConfig, err := TLSConfig(envConfig)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
+	}
+
+	if envConfig.Proxy != "" {
+		proxyURL, err := url.Parse(envConfig.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy %q: %w", envConfig.Proxy, err)
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return nil
+}
+
+// NewHTTPClient returns an HTTP client with the transport settings and
+// timeout of an environment. Certificate verification failures are reported
+// with ExplainError.
+func NewHTTPClient(envConfig *config.EnvironmentConfig) (*http.Client, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if err := Configure(t, envConfig); err != nil {
+		return nil, err
+	}
+
+	timeout, err := envConfig.GetTimeout()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &explainingTransport{base: t},
+		Timeout:   timeout,
+	}, nil
+}
+
+// explainingTransport replaces certificate verification errors with ones
+// that name the setting to fix
+type explainingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *explainingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, ExplainError(err, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// IsVerificationError reports whether err is a failed TLS certificate verification
+func IsVerificationError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var verification *tls.CertificateVerificationError
+
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) ||
+		errors.As(err, &invalid) || errors.As(err, &verification)
+}
+
+// ExplainError turns a TLS failure talking to address into an error that says
+// which setting to check. Other errors are returned unchanged.
+func ExplainError(err error, address string) error {
+	if err == nil {
+		return nil
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("TLS verification of %s failed: the server certificate is signed by an unknown authority, set 'ca_cert' or 'ca_path' to the CA that issued it: %w", address, err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("TLS verification of %s failed: the server certificate is not valid for %s, check the url or set 'tls_server_name': %w", address, hostname.Host, err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("TLS verification of %s failed: the server certificate is invalid: %w", address, err)
+	case IsVerificationError(err):
+		return fmt.Errorf("TLS verification of %s failed: %w", address, err)
+	case strings.Contains(err.Error(), "tls: certificate required") || strings.Contains(err.Error(), "tls: bad certificate"):
+		return fmt.Errorf("TLS handshake with %s failed: the server requires a client certificate it trusts, check 'client_cert' and 'client_key': %w", address, err)
+	}
+
+	return err
+}
@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	vault "github.com/hashicorp/vault/api"
+	"github.com/secretz/vault-promoter/pkg/config"
+)
+
+// defaultSTSRegion matches the global STS endpoint Vault verifies against by default
+const defaultSTSRegion = "us-east-1"
+
+// newAuthMethod returns the login for the environment's auth method, or nil
+// when it uses a static token. Credentials are read on every login, so
+// rotated files such as projected service account tokens are picked up.
+func newAuthMethod(auth *config.VaultAuthConfig) (vault.AuthMethod, error) {
+	if auth == nil {
+		return nil, nil
+	}
+
+	switch auth.Method {
+	case config.AuthToken:
+		return nil, nil
+	case config.AuthAppRole:
+		return &appRoleAuth{auth: auth}, nil
+	case config.AuthKubernetes, config.AuthJWT:
+		return &jwtAuth{auth: auth}, nil
+	case config.AuthUserpass:
+		return &userpassAuth{auth: auth}, nil
+	case config.AuthAWS:
+		return &awsAuth{auth: auth}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth method: %s", auth.Method)
+	}
+}
+
+// login writes the credentials to the login endpoint of an auth mount
+func login(ctx context.Context, client *vault.Client, path string, data map[string]interface{}) (*vault.Secret, error) {
+	return client.Logical().WriteWithContext(ctx, "auth/"+path, data)
+}
+
+// appRoleAuth logs in with a role ID and an optional secret ID
+type appRoleAuth struct {
+	auth *config.VaultAuthConfig
+}
+
+func (a *appRoleAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	roleID, err := a.auth.GetRoleID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role ID: %w", err)
+	}
+
+	secretID, err := a.auth.GetSecretID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret ID: %w", err)
+	}
+
+	data := map[string]interface{}{"role_id": roleID}
+	if secretID != "" {
+		data["secret_id"] = secretID
+	}
+
+	return login(ctx, client, a.auth.GetMount()+"/login", data)
+}
+
+// jwtAuth logs in to the kubernetes or jwt/oidc method with a signed JWT,
+// such as a pod's service account token or a CI job's identity token
+type jwtAuth struct {
+	auth *config.VaultAuthConfig
+}
+
+func (a *jwtAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	jwt, err := a.auth.GetJWT()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JWT: %w", err)
+	}
+
+	return login(ctx, client, a.auth.GetMount()+"/login", map[string]interface{}{
+		"role": a.auth.Role,
+		"jwt":  jwt,
+	})
+}
+
+// userpassAuth logs in with a username and password
+type userpassAuth struct {
+	auth *config.VaultAuthConfig
+}
+
+func (a *userpassAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	password, err := a.auth.GetPassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password: %w", err)
+	}
+
+	return login(ctx, client, a.auth.GetMount()+"/login/"+a.auth.Username, map[string]interface{}{
+		"password": password,
+	})
+}
+
+// awsAuth logs in with the AWS IAM method. It signs an sts:GetCallerIdentity
+// request with the default AWS credentials and lets Vault send it to AWS,
+// so no AWS secret ever reaches Vault.
+type awsAuth struct {
+	auth *config.VaultAuthConfig
+}
+
+func (a *awsAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	region := a.auth.Region
+	if region == "" {
+		region = defaultSTSRegion
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	request, _ := sts.New(sess).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	if a.auth.ServerID != "" {
+		request.HTTPRequest.Header.Add("X-Vault-AWS-IAM-Server-ID", a.auth.ServerID)
+	}
+
+	if err := request.Sign(); err != nil {
+		return nil, fmt.Errorf("failed to sign sts:GetCallerIdentity request: %w", err)
+	}
+
+	headers, err := json.Marshal(request.HTTPRequest.Header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request headers: %w", err)
+	}
+
+	body, err := io.ReadAll(request.HTTPRequest.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	return login(ctx, client, a.auth.GetMount()+"/login", map[string]interface{}{
+		"role":                    a.auth.Role,
+		"iam_http_request_method": request.HTTPRequest.Method,
+		"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(request.HTTPRequest.URL.String())),
+		"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+		"iam_request_body":        base64.StdEncoding.EncodeToString(body),
+	})
+}
@@ -2,515 +2,461 @@ package vault
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"reflect"
+	"net/http"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	vault "github.com/hashicorp/vault/api"
 	"github.com/secretz/vault-promoter/pkg/config"
-	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/secretz/vault-promoter/pkg/store"
+	"github.com/secretz/vault-promoter/pkg/transport"
 )
 
+// Environment is the name of the environment a client is used for, one of
+// the promotion chain or any other configured name
 type Environment string
 
+// KV engine versions, as found in the "version" mount option
 const (
-	EnvDev  Environment = "dev"
-	EnvUAT  Environment = "uat"
-	EnvProd Environment = "prod"
+	kvVersion1 = "1"
+	kvVersion2 = "2"
 )
 
 type Client struct {
 	*vault.Client
-	env            Environment
-	kvEngine       string
-	redactedKeys   []string
-	redactSecrets  bool
-	redactJSONVals bool
-}
+	env       Environment
+	namespace string
+	kvEngine  string
+	kvVersion string // looked up from the mount on first use
 
-type SecretDiff struct {
-	Key        string
-	Current    string
-	Target     string
-	Diff       string
-	IsRedacted bool
-	Status     string // +, -, or * for added, removed, or modified
-}
-type SecretComparison struct {
-	Path  string
-	Diffs []SecretDiff
+	configuredKVVersion string // kv_version of the environment, used when the lookup is denied
+
+	authMethod  vault.AuthMethod // used to log in again, nil for static tokens
+	stopWatcher func()           // stops token renewal
+	watcherDone chan struct{}    // closed once token renewal has stopped
 }
 
-func NewClient(envConfig *config.EnvironmentConfig, configs *config.Configs, env Environment, kvEngine string) (*Client, error) {
+func NewClient(envConfig *config.EnvironmentConfig, env Environment, kvEngine string) (*Client, error) {
+	if kvEngine == "" {
+		return nil, fmt.Errorf("KV engine must be specified when using Vault")
+	}
+
 	config := vault.DefaultConfig()
 	config.Address = envConfig.URL
 
+	// Apply the environment's TLS, proxy and timeout settings
+	if err := transport.Configure(config.HttpClient.Transport.(*http.Transport), envConfig); err != nil {
+		return nil, fmt.Errorf("failed to configure vault transport: %w", err)
+	}
+
+	timeout, err := envConfig.GetTimeout()
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		config.Timeout = timeout
+	}
+
+	// A certificate that fails verification won't pass on a retry
+	config.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if transport.IsVerificationError(err) {
+			return false, err
+		}
+		return vault.DefaultRetryPolicy(ctx, resp, err)
+	}
+
 	client, err := vault.NewClient(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vault client: %w", err)
 	}
 
-	token, err := envConfig.GetVaultToken()
+	// The namespace header is sent with every request, so logins, reads,
+	// writes and mount operations all happen inside it
+	namespace := strings.Trim(envConfig.Namespace, "/")
+	if namespace != "" {
+		client.SetNamespace(namespace)
+	}
+
+	authMethod, err := newAuthMethod(envConfig.Auth)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get vault token: %w", err)
+		return nil, err
 	}
 
-	client.SetToken(token)
+	if authMethod == nil {
+		token, err := envConfig.GetVaultToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vault token: %w", err)
+		}
 
-	return &Client{
-		Client:         client,
-		env:            env,
-		kvEngine:       kvEngine,
-		redactedKeys:   configs.GetRedactedKeys(),
-		redactSecrets:  configs.ShouldRedactSecrets(),
-		redactJSONVals: configs.ShouldRedactJSONValues(),
-	}, nil
-}
+		client.SetToken(token)
+	} else {
+		// Don't send a token picked up from VAULT_TOKEN along with the login
+		client.ClearToken()
 
-func (c *Client) GetSecret(path string) (*vault.KVSecret, error) {
-	auth := c.Client.Auth()
-	if auth == nil {
-		return nil, fmt.Errorf("failed to authenticate with Vault")
+		if _, err := client.Auth().Login(context.Background(), authMethod); err != nil {
+			return nil, fmt.Errorf("failed to log in to vault with %s auth: %w", envConfig.Auth.Method, transport.ExplainError(err, envConfig.URL))
+		}
 	}
 
-	// Check if KV engine exists
-	mountOutput, err := c.Sys().ListMounts()
-	if err != nil {
-		return nil, fmt.Errorf("failed to list vault mounts: %w", err)
+	c := &Client{
+		Client:     client,
+		env:        env,
+		namespace:  namespace,
+		kvEngine:   kvEngine,
+		authMethod: authMethod,
+
+		configuredKVVersion: envConfig.KVVersion,
 	}
 
-	// Ensure the KV engine exists and has a trailing slash
-	kvEnginePath := c.kvEngine
-	if !strings.HasSuffix(kvEnginePath, "/") {
-		kvEnginePath += "/"
+	// Check the token before any operation and keep it alive for long runs
+	if err := c.startTokenLifecycle(); err != nil {
+		return nil, err
 	}
 
-	// Check if the engine exists
-	if _, exists := mountOutput[kvEnginePath]; !exists {
-		return nil, fmt.Errorf("KV engine '%s' does not exist in Vault", c.kvEngine)
+	return c, nil
+}
+
+// Type returns the store type served by this client
+func (c *Client) Type() string {
+	return config.StoreVault
+}
+
+// mountVersion returns the KV version of the client's engine, looking it up once.
+// Mounts without a version option (including legacy "generic" mounts) are KV v1.
+func (c *Client) mountVersion() (string, error) {
+	if c.kvVersion != "" {
+		return c.kvVersion, nil
 	}
 
-	secret, err := c.KVv2(c.kvEngine).Get(context.Background(), path)
+	version, err := c.lookupMountVersion(c.kvEngine)
 	if err != nil {
-		// Check if the error is a 404, which means the secret doesn't exist
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
-			return nil, fmt.Errorf("secret not found: %s", path)
+		if !isPermissionDenied(err) {
+			return "", err
 		}
-		return nil, fmt.Errorf("failed to get secret: %w", err)
+		if c.configuredKVVersion == "" {
+			return "", fmt.Errorf("%w; set kv_version for this environment when its token can't look up the mount", err)
+		}
+		version = c.configuredKVVersion
 	}
-	return secret, nil
+
+	c.kvVersion = version
+	return c.kvVersion, nil
 }
 
-func (c *Client) CompareSecrets(appName string, targetEnv Environment, pathSuffix string) (*SecretComparison, error) {
-	// Validate that pathSuffix is one of the accepted values
-	validPaths := []string{"config", "configs", "secret", "secrets"}
-	isValidPath := false
-	for _, p := range validPaths {
-		if p == pathSuffix {
-			isValidPath = true
-			break
-		}
+// lookupMountVersion asks Vault for the KV version of a mount the way the
+// vault CLI does, through sys/internal/ui/mounts. Unlike listing sys/mounts,
+// it only needs a policy on some path inside the mount.
+func (c *Client) lookupMountVersion(kvEngine string) (string, error) {
+	engine := strings.Trim(kvEngine, "/")
+	mount, err := c.Logical().Read("sys/internal/ui/mounts/" + engine)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up KV engine '%s'%s: %w", engine, c.namespaceSuffix(), err)
 	}
-	if !isValidPath {
-		return nil, fmt.Errorf("invalid path suffix: %s, must be one of: /config, /configs, /secret, /secrets", pathSuffix)
+	if mount == nil || mount.Data == nil {
+		return "", fmt.Errorf("KV engine '%s' does not exist in Vault%s", engine, c.namespaceSuffix())
 	}
 
-	currentPath := fmt.Sprintf("%s/%s/%s", appName, c.env, pathSuffix)
-	targetPath := fmt.Sprintf("%s/%s/%s", appName, targetEnv, pathSuffix)
+	// A path below a mount resolves to that mount, so check it is the engine itself
+	if mountPath, ok := mount.Data["path"].(string); ok && strings.Trim(mountPath, "/") != engine {
+		return "", fmt.Errorf("KV engine '%s' does not exist in Vault%s", engine, c.namespaceSuffix())
+	}
 
-	comparison := &SecretComparison{
-		Path: currentPath,
+	if options, ok := mount.Data["options"].(map[string]interface{}); ok && options["version"] == kvVersion2 {
+		return kvVersion2, nil
 	}
+	return kvVersion1, nil
+}
 
-	currentSecrets, err := c.GetSecret(currentPath)
-	currentExists := true
-	if err != nil {
-		// Check if the error is because the secret doesn't exist
-		if strings.Contains(err.Error(), "secret not found") {
-			currentExists = false
-		} else {
-			return nil, fmt.Errorf("failed to get current secrets: %w", err)
-		}
+// isPermissionDenied reports whether Vault refused a request for lack of permissions
+func isPermissionDenied(err error) bool {
+	var responseErr *vault.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusForbidden
+}
+
+// namespaceSuffix names the client's namespace for error messages
+func (c *Client) namespaceSuffix() string {
+	if c.namespace == "" {
+		return ""
 	}
+	return fmt.Sprintf(" namespace '%s'", c.namespace)
+}
 
-	targetSecrets, err := c.GetSecret(targetPath)
-	targetExists := true
-	if err != nil {
-		// Check if the error is because the secret doesn't exist
-		if strings.Contains(err.Error(), "secret not found") {
-			targetExists = false
-		} else {
-			return nil, fmt.Errorf("failed to get target secrets: %w", err)
-		}
+// KVVersion returns the version ("1" or "2") of the client's KV engine
+func (c *Client) KVVersion() (string, error) {
+	return c.mountVersion()
+}
+
+// isNotFoundError checks whether a Vault API error means the path is empty
+func isNotFoundError(err error) bool {
+	return strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found")
+}
+
+// splitVersion splits a "path@12" selector into the path and the KV v2
+// version number, which is 0 when the path pins no version
+func splitVersion(path string) (string, int, error) {
+	path, version := store.SplitVersion(path)
+	if version == "" {
+		return path, 0, nil
 	}
 
-	// If neither exists, return early with a message
-	if !currentExists && !targetExists {
-		return nil, fmt.Errorf("secret %s doesn't exist in both environments: %s and %s", pathSuffix, c.env, targetEnv)
+	number, err := strconv.Atoi(version)
+	if err != nil || number < 1 {
+		return "", 0, fmt.Errorf("invalid KV version %q in %s@%s", version, path, version)
 	}
+	return path, number, nil
+}
 
-	// Handle case where current env doesn't have the secret
-	if !currentExists {
-		comparison.Path = targetPath
-		comparison.Diffs = append(comparison.Diffs, SecretDiff{
-			Key:        "INFO",
-			Current:    fmt.Sprintf("Secret doesn't exist in %s environment", c.env),
-			Target:     "",
-			IsRedacted: false,
-			Status:     "-",
-		})
+// GetSecret reads the latest version of a secret, or the version pinned with "path@12"
+func (c *Client) GetSecret(path string) (*store.Secret, error) {
+	version, err := c.mountVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	secretPath, pinned, err := splitVersion(path)
+	if err != nil {
+		return nil, err
+	}
 
-		// List all target keys and values
-		for key, targetValue := range targetSecrets.Data {
-			targetValueStr := fmt.Sprintf("%v", targetValue)
-			redacted := c.isRedactedKey(key)
-
-			// Check if value is JSON and should be redacted
-			redactedJSON, isJSON := c.TryParseAndRedactJSON(targetValueStr)
-			if isJSON {
-				targetValueStr = redactedJSON
-			}
-
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    "", // No current value
-				Target:     targetValueStr,
-				IsRedacted: redacted,
-				Status:     "-",
-			})
+	var secret *vault.KVSecret
+	switch {
+	case version == kvVersion1 && pinned > 0:
+		return nil, fmt.Errorf("KV v1 engine %s keeps no versions, cannot read %s", c.kvEngine, path)
+	case version == kvVersion1:
+		secret, err = c.KVv1(c.kvEngine).Get(context.Background(), secretPath)
+	case pinned > 0:
+		secret, err = c.KVv2(c.kvEngine).GetVersion(context.Background(), secretPath, pinned)
+	default:
+		secret, err = c.KVv2(c.kvEngine).Get(context.Background(), secretPath)
+	}
+	if err != nil {
+		// Check if the error is a 404, which means the secret doesn't exist
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
 		}
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
 
-		return comparison, nil
+	// A deleted version comes back without data
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
 	}
 
-	// Handle case where target env doesn't have the secret
-	if !targetExists {
-		comparison.Diffs = append(comparison.Diffs, SecretDiff{
-			Key:        "INFO",
-			Current:    "",
-			Target:     fmt.Sprintf("Secret doesn't exist in %s environment", targetEnv),
-			IsRedacted: false,
-			Status:     "+",
-		})
+	return store.SecretFromData(secret.Data), nil
+}
 
-		// List all current keys and values
-		for key, currentValue := range currentSecrets.Data {
-			currentValueStr := fmt.Sprintf("%v", currentValue)
-			redacted := c.isRedactedKey(key)
-
-			// Check if value is JSON and should be redacted
-			redactedJSON, isJSON := c.TryParseAndRedactJSON(currentValueStr)
-			if isJSON {
-				currentValueStr = redactedJSON
-			}
-
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    currentValueStr,
-				Target:     "", // No target value
-				IsRedacted: redacted,
-				Status:     "+",
-			})
-		}
+// SupportsBinary reports that binary secrets can be written to Vault
+func (c *Client) SupportsBinary() bool {
+	return true
+}
 
-		return comparison, nil
+// WriteSecret writes a secret to the specified path
+func (c *Client) WriteSecret(path string, secret *store.Secret) error {
+	if _, version := store.SplitVersion(path); version != "" {
+		return fmt.Errorf("cannot write to a pinned version: %s", path)
 	}
 
-	// Both secrets exist, compare them
-	processedKeys := make(map[string]bool)
-
-	for key, currentValue := range currentSecrets.Data {
-		processedKeys[key] = true
-		targetValue, exists := targetSecrets.Data[key]
-		if !exists {
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    fmt.Sprintf("%v", currentValue),
-				Target:     "",
-				IsRedacted: c.isRedactedKey(key) || strings.Contains(pathSuffix, "secret"),
-				Status:     "+",
-			})
-			continue
-		}
+	version, err := c.mountVersion()
+	if err != nil {
+		return err
+	}
 
-		currentValueStr := fmt.Sprintf("%v", currentValue)
-		targetValueStr := fmt.Sprintf("%v", targetValue)
+	// Binary secrets are stored base64-encoded with a marker so they read back as bytes
+	data := secret.Data
+	if secret.Binary {
+		data = store.BinaryData(secret)
+	}
 
-		redacted := c.isRedactedKey(key)
+	// Write the secret
+	if version == kvVersion1 {
+		err = c.KVv1(c.kvEngine).Put(context.Background(), path, data)
+	} else {
+		_, err = c.KVv2(c.kvEngine).Put(context.Background(), path, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write secret: %w", err)
+	}
 
-		// Check if values are JSON and should be redacted
-		redactedCurrentJSON, isCurrentJSON := c.TryParseAndRedactJSON(currentValueStr)
-		if isCurrentJSON {
-			currentValueStr = redactedCurrentJSON
-		}
+	return nil
+}
 
-		redactedTargetJSON, isTargetJSON := c.TryParseAndRedactJSON(targetValueStr)
-		if isTargetJSON {
-			targetValueStr = redactedTargetJSON
-		}
+// ListSecrets lists the secrets and folders directly under prefix
+func (c *Client) ListSecrets(prefix string) ([]string, error) {
+	version, err := c.mountVersion()
+	if err != nil {
+		return nil, err
+	}
 
-		if currentValueStr != targetValueStr {
-			// Generate diff only if not redacted
-			diffText := ""
-			if !redacted {
-				diffText = GenerateDiff(currentValueStr, targetValueStr)
-			}
-
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    currentValueStr,
-				Target:     targetValueStr,
-				Diff:       diffText,
-				IsRedacted: redacted,
-				Status:     "*", // Modified value
-			})
-		}
+	// KV v2 lists keys through the metadata endpoint
+	prefix = strings.Trim(prefix, "/")
+	listPath := fmt.Sprintf("%s/%s", strings.Trim(c.kvEngine, "/"), prefix)
+	if version == kvVersion2 {
+		listPath = fmt.Sprintf("%s/metadata/%s", strings.Trim(c.kvEngine, "/"), prefix)
 	}
 
-	for key, targetValue := range targetSecrets.Data {
-		if _, exists := processedKeys[key]; !exists {
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    "",
-				Target:     fmt.Sprintf("%v", targetValue),
-				IsRedacted: c.isRedactedKey(key) || strings.Contains(pathSuffix, "secret"),
-				Status:     "-",
-			})
-		}
+	secret, err := c.Logical().List(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
 	}
 
-	return comparison, nil
-}
+	// Vault returns no secret at all for an empty folder
+	if secret == nil || secret.Data == nil {
+		return []string{}, nil
+	}
 
-func (c *Client) isRedactedKey(key string) bool {
-	if !c.redactSecrets {
-		return false
+	keys, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return []string{}, nil
 	}
 
-	lowerKey := strings.ToLower(key)
-	for _, redactedKey := range c.redactedKeys {
-		if strings.Contains(lowerKey, strings.ToLower(redactedKey)) {
-			return true
+	paths := make([]string, 0, len(keys))
+	for _, key := range keys {
+		name := fmt.Sprintf("%v", key)
+		entry := path.Join(prefix, name)
+		if strings.HasSuffix(name, "/") {
+			entry += "/"
 		}
+		paths = append(paths, entry)
 	}
-	return false
-}
 
-// IsJSONValue checks if a string is a valid JSON object or array
-func IsJSONValue(s string) bool {
-	s = strings.TrimSpace(s)
-	return (strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")) || (strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"))
+	return paths, nil
 }
 
-// RedactJSONValues recursively goes through a JSON object and redacts values with sensitive keys
-func (c *Client) RedactJSONValues(data interface{}) interface{} {
-	if !c.redactJSONVals {
-		return data
-	}
-
-	switch v := data.(type) {
-	case map[string]interface{}:
-		result := make(map[string]interface{})
-		for key, value := range v {
-			if c.isRedactedKey(key) {
-				result[key] = "****"
-			} else {
-				result[key] = c.RedactJSONValues(value)
-			}
-		}
-		return result
-	case []interface{}:
-		result := make([]interface{}, len(v))
-		for i, item := range v {
-			result[i] = c.RedactJSONValues(item)
-		}
-		return result
-	default:
-		return v
+// DeleteSecret soft-deletes the latest version of a KV v2 secret so it can still be
+// recovered. KV v1 has no versions, so the secret is removed for good.
+func (c *Client) DeleteSecret(path string) error {
+	if _, version := store.SplitVersion(path); version != "" {
+		return fmt.Errorf("cannot delete a pinned version: %s", path)
 	}
-}
 
-// CompareSecretPaths compares secrets between two full paths
-func (c *Client) CompareSecretPaths(sourcePath, targetPath string) (*SecretComparison, error) {
-	// Get the current secrets
-	currentSecrets, err := c.GetSecret(sourcePath)
+	version, err := c.mountVersion()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current secrets: %w", err)
+		return err
 	}
 
-	// Get the target secrets
-	targetSecrets, err := c.GetSecret(targetPath)
+	if version == kvVersion1 {
+		err = c.KVv1(c.kvEngine).Delete(context.Background(), path)
+	} else {
+		err = c.KVv2(c.kvEngine).Delete(context.Background(), path)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get target secrets: %w", err)
-	}
-
-	// Create a comparison object
-	comparison := &SecretComparison{
-		Path:  sourcePath,
-		Diffs: []SecretDiff{},
-	}
-
-	// Track processed keys to avoid duplicates
-	processedKeys := make(map[string]bool)
-
-	// Compare current secrets with target secrets
-	for key, currentValue := range currentSecrets.Data {
-		processedKeys[key] = true
-
-		// Convert value to string for comparison
-		currentValueStr := fmt.Sprintf("%v", currentValue)
-
-		// Check if the key exists in target secrets
-		targetValue, exists := targetSecrets.Data[key]
-		if !exists {
-			// Key only exists in current secrets (added)
-			// Check if the key should be redacted
-			redacted := c.isRedactedKey(key)
-
-			// Try to parse and redact JSON values if needed
-			if redacted && c.redactJSONVals {
-				redactedJSON, isJSON := c.TryParseAndRedactJSON(currentValueStr)
-				if isJSON {
-					currentValueStr = redactedJSON
-				}
-			}
-
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    currentValueStr,
-				Target:     "",
-				IsRedacted: redacted,
-				Status:     "+", // Added key
-			})
-		} else {
-			// Key exists in both, compare values
-			targetValueStr := fmt.Sprintf("%v", targetValue)
-
-			// Skip if values are identical
-			if currentValueStr == targetValueStr {
-				continue
-			}
-
-			// Check if the key should be redacted
-			redacted := c.isRedactedKey(key)
-
-			// Try to parse and redact JSON values if needed
-			if redacted && c.redactJSONVals {
-				redactedCurrentJSON, isCurrentJSON := c.TryParseAndRedactJSON(currentValueStr)
-				if isCurrentJSON {
-					currentValueStr = redactedCurrentJSON
-				}
-
-				redactedTargetJSON, isTargetJSON := c.TryParseAndRedactJSON(targetValueStr)
-				if isTargetJSON {
-					targetValueStr = redactedTargetJSON
-				}
-			}
-
-			// Generate diff only if not redacted
-			diffText := ""
-			if !redacted {
-				diffText = GenerateDiff(currentValueStr, targetValueStr)
-			}
-
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    currentValueStr,
-				Target:     targetValueStr,
-				Diff:       diffText,
-				IsRedacted: redacted,
-				Status:     "*", // Modified value
-			})
+		if isNotFoundError(err) {
+			return fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
 		}
+		return fmt.Errorf("failed to delete secret: %w", err)
 	}
 
-	// Find keys that only exist in target secrets (removed)
-	for key, targetValue := range targetSecrets.Data {
-		if _, exists := processedKeys[key]; !exists {
-			// Key only exists in target secrets (removed)
-			targetValueStr := fmt.Sprintf("%v", targetValue)
-
-			// Check if the key should be redacted
-			redacted := c.isRedactedKey(key)
-
-			// Try to parse and redact JSON values if needed
-			if redacted && c.redactJSONVals {
-				redactedJSON, isJSON := c.TryParseAndRedactJSON(targetValueStr)
-				if isJSON {
-					targetValueStr = redactedJSON
-				}
-			}
-
-			comparison.Diffs = append(comparison.Diffs, SecretDiff{
-				Key:        key,
-				Current:    "",
-				Target:     targetValueStr,
-				IsRedacted: redacted,
-				Status:     "-", // Removed key
-			})
+	return nil
+}
+
+// SecretExists checks whether a secret is stored at path
+func (c *Client) SecretExists(path string) (bool, error) {
+	_, err := c.GetSecret(path)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return false, nil
 		}
+		return false, err
 	}
-
-	return comparison, nil
+	return true, nil
 }
 
-// TryParseAndRedactJSON attempts to parse a string as JSON and redact sensitive values
-func (c *Client) TryParseAndRedactJSON(value string) (string, bool) {
-	if !c.redactJSONVals || !IsJSONValue(value) {
-		return value, false
+// GetMetadata returns the KV v2 metadata of the secret. KV v1 keeps no metadata,
+// so only the existence of the secret is checked there.
+func (c *Client) GetMetadata(path string) (*store.Metadata, error) {
+	version, err := c.mountVersion()
+	if err != nil {
+		return nil, err
 	}
 
-	var data interface{}
-	err := json.Unmarshal([]byte(value), &data)
-	if err != nil {
-		return value, false
+	if version == kvVersion1 {
+		if _, err := c.GetSecret(path); err != nil {
+			return nil, err
+		}
+		return &store.Metadata{Path: path, Labels: map[string]string{"kv_version": kvVersion1}}, nil
 	}
 
-	redactedData := c.RedactJSONValues(data)
-	if reflect.DeepEqual(data, redactedData) {
-		return value, false
+	secretPath, pinned, err := splitVersion(path)
+	if err != nil {
+		return nil, err
 	}
 
-	redactedJSON, err := json.MarshalIndent(redactedData, "", "  ")
+	metadata, err := c.KVv2(c.kvEngine).GetMetadata(context.Background(), secretPath)
 	if err != nil {
-		return value, false
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to get secret metadata: %w", err)
 	}
 
-	return string(redactedJSON), true
-}
+	labels := make(map[string]string)
+	for k, v := range metadata.CustomMetadata {
+		labels[k] = fmt.Sprintf("%v", v)
+	}
 
-// GenerateDiff creates a text diff between two strings
-func GenerateDiff(current, target string) string {
-	dmp := diffmatchpatch.New()
-	diffs := dmp.DiffMain(current, target, false)
-	return dmp.DiffPrettyText(diffs)
+	if pinned > 0 {
+		versionMetadata, ok := metadata.Versions[strconv.Itoa(pinned)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+
+		if !versionMetadata.DeletionTime.IsZero() {
+			labels["deleted"] = versionMetadata.DeletionTime.Format(time.RFC3339)
+		}
+		if versionMetadata.Destroyed {
+			labels["destroyed"] = "true"
+		}
+
+		return &store.Metadata{
+			Path:        path,
+			Version:     strconv.Itoa(pinned),
+			CreatedTime: versionMetadata.CreatedTime,
+			UpdatedTime: versionMetadata.CreatedTime,
+			Labels:      labels,
+		}, nil
+	}
+
+	return &store.Metadata{
+		Path:        path,
+		Version:     fmt.Sprintf("%d", metadata.CurrentVersion),
+		CreatedTime: metadata.CreatedTime,
+		UpdatedTime: metadata.UpdatedTime,
+		Labels:      labels,
+	}, nil
 }
 
-// WriteSecret writes a secret to the specified path
-func (c *Client) WriteSecret(path string, data map[string]interface{}) error {
-	// Check if KV engine exists
-	mountOutput, err := c.Sys().ListMounts()
+// ListVersions returns the KV v2 versions of a secret, oldest first.
+// Deleted and destroyed versions are listed but can't be read.
+func (c *Client) ListVersions(path string) ([]store.VersionInfo, error) {
+	version, err := c.mountVersion()
 	if err != nil {
-		return fmt.Errorf("failed to list vault mounts: %w", err)
+		return nil, err
 	}
 
-	// Ensure the KV engine exists and has a trailing slash
-	kvEnginePath := c.kvEngine
-	if !strings.HasSuffix(kvEnginePath, "/") {
-		kvEnginePath += "/"
+	if version == kvVersion1 {
+		return nil, fmt.Errorf("KV v1 engine %s keeps no version history", c.kvEngine)
 	}
 
-	// Check if the engine exists
-	if _, exists := mountOutput[kvEnginePath]; !exists {
-		return fmt.Errorf("KV engine '%s' does not exist in Vault", c.kvEngine)
+	versions, err := c.KVv2(c.kvEngine).GetVersionsAsList(context.Background(), path)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", store.ErrSecretNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to list secret versions: %w", err)
 	}
 
-	// Write the secret
-	_, err = c.KVv2(c.kvEngine).Put(context.Background(), path, data)
-	if err != nil {
-		return fmt.Errorf("failed to write secret: %w", err)
+	infos := make([]store.VersionInfo, 0, len(versions))
+	for _, v := range versions {
+		infos = append(infos, store.VersionInfo{
+			Version:     strconv.Itoa(v.Version),
+			CreatedTime: v.CreatedTime,
+			Deleted:     v.Destroyed || !v.DeletionTime.IsZero(),
+		})
 	}
 
-	return nil
+	return infos, nil
 }
@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// EnsureKVEngineExists ensures that the KV engine exists in Vault, inside the
+// client's namespace when one is configured.
+// Missing engines are mounted as KV v2; existing ones are used as they are.
+func (c *Client) EnsureKVEngineExists(kvEngine string) error {
+	// Tokens allowed to use an engine can see it without listing every mount.
+	// When even that is denied, the engine is left to the write to check.
+	if _, err := c.lookupMountVersion(kvEngine); err == nil || isPermissionDenied(err) {
+		return nil
+	}
+
+	// Check if KV engine exists
+	mountOutput, err := c.Sys().ListMounts()
+	if err != nil {
+		return fmt.Errorf("failed to list vault mounts: %w", err)
+	}
+
+	// Ensure the KV engine exists and has a trailing slash
+	kvEnginePath := kvEngine
+	if !strings.HasSuffix(kvEnginePath, "/") {
+		kvEnginePath += "/"
+	}
+
+	// Check if the engine exists
+	if _, exists := mountOutput[kvEnginePath]; !exists {
+		// Create the KV engine if it doesn't exist
+		options := &vault.MountInput{
+			Type:    "kv",
+			Options: map[string]string{"version": "2"},
+		}
+
+		err := c.Sys().Mount(kvEnginePath, options)
+		if err != nil {
+			return fmt.Errorf("failed to create KV engine '%s' in Vault%s: %w", kvEngine, c.namespaceSuffix(), err)
+		}
+
+		if strings.Trim(kvEngine, "/") == strings.Trim(c.kvEngine, "/") {
+			c.kvVersion = kvVersion2
+		}
+	}
+
+	return nil
+}
+
+// Provision creates the client's KV engine if it is missing
+func (c *Client) Provision() error {
+	return c.EnsureKVEngineExists(c.kvEngine)
+}
@@ -0,0 +1,176 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/secretz/vault-promoter/pkg/transport"
+)
+
+// expiryWarning is how close to expiry a token must be for a warning
+const expiryWarning = 10 * time.Minute
+
+// startTokenLifecycle looks up the client's token and keeps it alive.
+// Renewable tokens are renewed by a lifetime watcher, and tokens from an auth
+// method are replaced by logging in again once they can't be renewed further.
+func (c *Client) startTokenLifecycle() error {
+	lookup, err := c.Auth().Token().LookupSelf()
+	if err != nil {
+		if transport.IsVerificationError(err) {
+			return transport.ExplainError(err, c.Address())
+		}
+		return fmt.Errorf("failed to look up vault token, it may be invalid or expired: %w", err)
+	}
+
+	ttl, err := lookup.TokenTTL()
+	if err != nil {
+		return fmt.Errorf("failed to read vault token TTL: %w", err)
+	}
+
+	renewable, err := lookup.TokenIsRenewable()
+	if err != nil {
+		return fmt.Errorf("failed to read vault token renewability: %w", err)
+	}
+
+	// Tokens without a TTL, such as root tokens, never expire
+	if ttl == 0 {
+		return nil
+	}
+
+	if !renewable && c.authMethod == nil {
+		if ttl < expiryWarning {
+			fmt.Fprintf(os.Stderr, "WARNING: Vault token for %s expires in %s and can't be renewed\n", c.Address(), ttl.Round(time.Second))
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.stopWatcher = cancel
+	c.watcherDone = make(chan struct{})
+
+	go func() {
+		defer close(c.watcherDone)
+		c.watchToken(ctx, &vault.Secret{
+			Auth: &vault.SecretAuth{
+				ClientToken:   c.Token(),
+				Renewable:     renewable,
+				LeaseDuration: int(ttl.Seconds()),
+			},
+		})
+	}()
+
+	return nil
+}
+
+// watchToken renews the token until it reaches its max TTL, then logs in
+// again if the environment has an auth method. It runs until ctx is done.
+func (c *Client) watchToken(ctx context.Context, secret *vault.Secret) {
+	for {
+		watcher, err := c.NewLifetimeWatcher(&vault.LifetimeWatcherInput{
+			Secret:        secret,
+			RenewBehavior: vault.RenewBehaviorIgnoreErrors,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: failed to watch Vault token for %s: %v\n", c.Address(), err)
+			return
+		}
+
+		go watcher.Start()
+
+		select {
+		case <-ctx.Done():
+			watcher.Stop()
+			return
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: failed to renew Vault token for %s: %v\n", c.Address(), err)
+			}
+		}
+
+		if c.authMethod == nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Vault token for %s can't be renewed further and is about to expire\n", c.Address())
+			return
+		}
+
+		secret, err = c.Auth().Login(ctx, c.authMethod)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "WARNING: failed to log in to Vault again for %s: %v\n", c.Address(), err)
+			return
+		}
+	}
+}
+
+// Close stops renewing the client's token and waits for the renewal to end,
+// so nothing is logged once the command is done. It is safe to call twice.
+func (c *Client) Close() error {
+	if c.stopWatcher == nil {
+		return nil
+	}
+
+	c.stopWatcher()
+	<-c.watcherDone
+	c.stopWatcher = nil
+	return nil
+}
+
+// apiPath returns the API path a KV operation on path is authorized against
+func (c *Client) apiPath(secretPath string) (string, error) {
+	version, err := c.mountVersion()
+	if err != nil {
+		return "", err
+	}
+
+	engine := strings.Trim(c.kvEngine, "/")
+	if version == kvVersion2 {
+		return path.Join(engine, "data", secretPath), nil
+	}
+	return path.Join(engine, secretPath), nil
+}
+
+// CheckAccess checks that the token's policies allow reading path, or writing
+// it when write is set, so a missing capability is reported before a run starts
+func (c *Client) CheckAccess(secretPath string, write bool) error {
+	secretPath, _, err := splitVersion(secretPath)
+	if err != nil {
+		return err
+	}
+
+	apiPath, err := c.apiPath(secretPath)
+	if err != nil {
+		return err
+	}
+
+	capabilities, err := c.Sys().CapabilitiesSelf(apiPath)
+	if err != nil {
+		return fmt.Errorf("failed to check vault token capabilities on %s: %w", apiPath, err)
+	}
+
+	granted := make(map[string]bool)
+	for _, capability := range capabilities {
+		granted[capability] = true
+	}
+
+	if granted["root"] {
+		return nil
+	}
+
+	if write {
+		if !granted["create"] && !granted["update"] {
+			return fmt.Errorf("vault token for %s can't write %s (capabilities: %s)", c.Address(), apiPath, strings.Join(capabilities, ", "))
+		}
+		return nil
+	}
+
+	if !granted["read"] {
+		return fmt.Errorf("vault token for %s can't read %s (capabilities: %s)", c.Address(), apiPath, strings.Join(capabilities, ", "))
+	}
+	return nil
+}